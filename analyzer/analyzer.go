@@ -0,0 +1,111 @@
+// Package analyzer provides gorder as an analysis.Analyzer. It reports files
+// whose declarations are out of order, with a suggested fix that reorders
+// them. Generated and cgo files and files with //line directives are left
+// as they are.
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"os"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer reports out of order declarations using gorder.DefaultOptions.
+var Analyzer = New(gorder.DefaultOptions())
+
+// New creates an analyzer reporting out of order declarations using opts.
+func New(opts gorder.Options) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: "gorder",
+		Doc:  "report declarations out of gorder order",
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			return nil, run(pass, opts)
+		},
+	}
+}
+
+func run(pass *analysis.Pass, opts gorder.Options) error {
+	for _, f := range pass.Files {
+		tf := pass.Fset.File(f.Pos())
+		// Leave out the files cgo writes to the build cache, which are not
+		// named *.go.
+		if tf == nil || !strings.HasSuffix(tf.Name(), ".go") || skip(f) {
+			continue
+		}
+
+		src, err := os.ReadFile(tf.Name())
+		if err != nil {
+			return err
+		}
+		if gorder.HasLineDirectives(src) {
+			continue
+		}
+
+		// A file gorder can't handle should not fail the analysis of the
+		// other files in the package, so leave it as is.
+		r, err := gorder.Reorder(tf.Name(), src, opts)
+		if err != nil {
+			continue
+		}
+
+		if bytes.Equal(src, r.Src) {
+			continue
+		}
+
+		// Report at the first declaration that moved, or else at the first
+		// line that changes, e.g. in a sorted interface.
+		pos, message := f.Package, "declarations are out of order"
+		if len(r.Moves) > 0 && r.Moves[0].OldIndex < len(f.Decls) {
+			m := r.Moves[0]
+			name := m.Name
+			if m.Receiver != "" {
+				name = m.Receiver + "." + name
+			}
+			pos = f.Decls[m.OldIndex].Pos()
+			message = fmt.Sprintf("%s %s is out of order", m.Kind, name)
+		} else {
+			offset := 0
+			for offset < len(src) && offset < len(r.Src) && src[offset] == r.Src[offset] {
+				offset++
+			}
+			pos = tf.Pos(bytes.LastIndexByte(src[:offset], '\n') + 1)
+		}
+
+		start := tf.Pos(0)
+		end := tf.Pos(tf.Size())
+
+		pass.Report(analysis.Diagnostic{
+			Pos:     pos,
+			Message: message,
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message: "Reorder declarations",
+				TextEdits: []analysis.TextEdit{{
+					Pos:     start,
+					End:     end,
+					NewText: r.Src,
+				}},
+			}},
+		})
+	}
+
+	return nil
+}
+
+// skip reports whether f is left as is, as the gorder command does by
+// default: generated and cgo files.
+func skip(f *ast.File) bool {
+	if ast.IsGenerated(f) {
+		return true
+	}
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package analyzer_test
+
+import (
+	"testing"
+
+	"github.com/bep/gorder/analyzer"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// The generated, cgo and //line files in testdata are out of order too,
+// but left as is.
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), analyzer.Analyzer, "a")
+}
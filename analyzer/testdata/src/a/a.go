@@ -0,0 +1,5 @@
+package a
+
+func zed() {} // want `func zed is out of order`
+
+func Alpha() {}
@@ -0,0 +1,8 @@
+package a
+
+// #include <stdlib.h>
+import "C"
+
+func cgoZed() {}
+
+func CgoAlpha() {}
@@ -0,0 +1,7 @@
+package a
+
+//line parser.y:10
+func lineZed() {}
+
+//line parser.y:20
+func LineAlpha() {}
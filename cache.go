@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// fileCache records the files known to be in order with -cache, keyed by
+// their content, their config and the gorder version, so later runs skip
+// them without parsing.
+type fileCache struct {
+	dir  string
+	tool string
+}
+
+func newFileCache(dir string) (*fileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileCache{dir: dir, tool: cacheToolKey()}, nil
+}
+
+// cacheToolKey identifies the gorder build. Development builds without a
+// revision are told apart by the modification time of the executable.
+func cacheToolKey() string {
+	t := toolInfo()
+	key := strings.Join([]string{t.Version, t.Revision, t.Go}, " ")
+	if t.Version == "(devel)" && t.Revision == "" {
+		if exe, err := os.Executable(); err == nil {
+			if fi, err := os.Stat(exe); err == nil {
+				key += " " + fi.ModTime().String()
+			}
+		}
+	}
+	return key
+}
+
+// cacheable reports whether the result for a file with c only depends on
+// its content, and not on other files, the git history or a diff.
+func cacheable(c config) bool {
+	return !c.Align && !c.Insert && !c.GRPC && c.changed == nil
+}
+
+// key returns the cache key of the file with the source src and config c.
+func (fc *fileCache) key(src []byte, c config) (string, error) {
+	b, err := toml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(fc.tool), b, src} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (fc *fileCache) path(key string) string {
+	return filepath.Join(fc.dir, key[:2], key)
+}
+
+// ordered reports whether the file with key is known to be in order.
+func (fc *fileCache) ordered(key string) bool {
+	_, err := os.Stat(fc.path(key))
+	return err == nil
+}
+
+// markOrdered records the file with key as in order. Errors are ignored, as
+// the cache only saves time.
+func (fc *fileCache) markOrdered(key string) {
+	p := fc.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err == nil {
+		os.WriteFile(p, nil, 0o644)
+	}
+}
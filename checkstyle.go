@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// The checkstyle report written with -format=checkstyle.
+type (
+	checkstyleReport struct {
+		XMLName xml.Name         `xml:"checkstyle"`
+		Version string           `xml:"version,attr"`
+		Files   []checkstyleFile `xml:"file"`
+	}
+
+	checkstyleFile struct {
+		Name   string            `xml:"name,attr"`
+		Errors []checkstyleError `xml:"error"`
+	}
+
+	checkstyleError struct {
+		Line     int    `xml:"line,attr"`
+		Column   int    `xml:"column,attr,omitempty"`
+		Severity string `xml:"severity,attr"`
+		Message  string `xml:"message,attr"`
+		Source   string `xml:"source,attr"`
+	}
+)
+
+// writeCheckstyle writes reports to w as a checkstyle XML report, with a
+// warning per finding and an error per error.
+func writeCheckstyle(w io.Writer, reports []report) error {
+	cs := checkstyleReport{Version: "5.0"}
+	for _, r := range reports {
+		f := checkstyleFile{Name: r.File}
+		for _, fi := range r.findings() {
+			f.Errors = append(f.Errors, checkstyleError{
+				Line:     fi.line,
+				Severity: "warning",
+				Message:  fi.message,
+				Source:   "gorder." + fi.rule,
+			})
+		}
+		if r.Error != nil {
+			f.Errors = append(f.Errors, checkstyleError{
+				Line:     r.Error.Line,
+				Column:   r.Error.Column,
+				Severity: "error",
+				Message:  r.Error.Message,
+				Source:   "gorder",
+			})
+		}
+		cs.Files = append(cs.Files, f)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(cs); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
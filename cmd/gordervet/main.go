@@ -0,0 +1,12 @@
+// Command gordervet runs the gorder analyzer, e.g. with
+// go vet -vettool=$(which gordervet) ./...
+package main
+
+import (
+	"github.com/bep/gorder/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
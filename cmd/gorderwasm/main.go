@@ -0,0 +1,89 @@
+//go:build js && wasm
+
+// Command gorderwasm exposes gorder to JavaScript as gorder.order(source,
+// options), e.g. for a playground running in the browser. Build it with
+// GOOS=js GOARCH=wasm go build -o gorder.wasm ./cmd/gorderwasm and load it
+// with the wasm_exec.js of the Go release.
+//
+// The options are the fields of gorder.Options, e.g. {mode: "caller",
+// structFields: true}, with the kinds to sort as only, e.g. only: ["func"],
+// and the file name, used for the _test.go files and in the errors, as
+// filename. The result is an object holding the reordered source, src, and
+// the moves, or the error, error.
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"syscall/js"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// jsOptions are the options passed from JavaScript.
+type jsOptions struct {
+	gorder.Options
+	Only     []string `json:"only"`
+	Filename string   `json:"filename"`
+}
+
+// jsResult is the result returned to JavaScript.
+type jsResult struct {
+	Src   string        `json:"src,omitempty"`
+	Moves []gorder.Move `json:"moves,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+func main() {
+	js.Global().Set("gorder", js.ValueOf(map[string]any{
+		"order": js.FuncOf(order),
+	}))
+	select {}
+}
+
+// order is gorder.order(source, options), options optional.
+func order(this js.Value, args []js.Value) any {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return toJS(jsResult{Error: "gorder.order: expected the source as a string"})
+	}
+	var opts js.Value
+	if len(args) > 1 {
+		opts = args[1]
+	}
+
+	r, err := reorder(args[0].String(), opts)
+	if err != nil {
+		return toJS(jsResult{Error: err.Error()})
+	}
+	return toJS(jsResult{Src: string(r.Src), Moves: r.Moves})
+}
+
+func reorder(src string, v js.Value) (gorder.Result, error) {
+	o := jsOptions{Options: gorder.DefaultOptions()}
+	if v.Truthy() {
+		b := js.Global().Get("JSON").Call("stringify", v).String()
+		if err := json.Unmarshal([]byte(b), &o); err != nil {
+			return gorder.Result{}, err
+		}
+	}
+	if o.Only != nil {
+		kinds, err := gorder.ParseKinds(strings.Join(o.Only, ","))
+		if err != nil {
+			return gorder.Result{}, err
+		}
+		o.Kinds = kinds
+	}
+	if err := o.Validate(); err != nil {
+		return gorder.Result{}, err
+	}
+	return gorder.Reorder(o.Filename, []byte(src), o.Options)
+}
+
+// toJS converts r to a JavaScript object.
+func toJS(r jsResult) js.Value {
+	b, err := json.Marshal(r)
+	if err != nil {
+		b, _ = json.Marshal(jsResult{Error: err.Error()})
+	}
+	return js.Global().Get("JSON").Call("parse", string(b))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// The -color values.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// useColor reports whether to colorize the output to stdout with the -color
+// value mode. With auto, stdout must be a terminal and NO_COLOR unset.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto:
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := os.Stdout.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return fi.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("invalid -color value %q", mode)
+	}
+}
+
+// colorizer formats the lines of a diff, with ANSI colors if set.
+type colorizer bool
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiCyan    = "\x1b[36m"
+	ansiBoldRed = "\x1b[1;31m"
+	ansiBoldGrn = "\x1b[1;32m"
+)
+
+func (c colorizer) wrap(code, line string) string {
+	if !c {
+		return line + "\n"
+	}
+	return code + line + ansiReset + "\n"
+}
+
+func (c colorizer) header(line string) string {
+	return c.wrap(ansiBold, line)
+}
+
+func (c colorizer) hunk(line string) string {
+	return c.wrap(ansiCyan, line)
+}
+
+// deleted formats a deleted line, highlighted if it starts a declaration
+// that moved.
+func (c colorizer) deleted(line string, moved bool) string {
+	if moved {
+		return c.wrap(ansiBoldRed, line)
+	}
+	return c.wrap(ansiRed, line)
+}
+
+// inserted formats an inserted line, highlighted if it starts a
+// declaration that moved.
+func (c colorizer) inserted(line string, moved bool) string {
+	if moved {
+		return c.wrap(ansiBoldGrn, line)
+	}
+	return c.wrap(ansiGreen, line)
+}
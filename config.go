@@ -0,0 +1,606 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	gofumpt "mvdan.cc/gofumpt/format"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// configName is the name of the config files looked for in the directory
+// of each file and its parents, up to the module root.
+const configName = ".gorder.toml"
+
+// configVersion is the current version of the config format. Bump it and add
+// a migration when a key is renamed or changes meaning.
+const configVersion = 1
+
+// migrations upgrade the keys of a config file from version i to i+1.
+var migrations = []func(m map[string]any){
+	// Files without a version predate versioning and match version 1.
+	func(m map[string]any) {},
+}
+
+// migrateConfig upgrades the config file b to configVersion. It returns the
+// upgraded keys and the version of b.
+func migrateConfig(b []byte) (map[string]any, int, error) {
+	var m map[string]any
+	if err := toml.Unmarshal(b, &m); err != nil {
+		return nil, 0, err
+	}
+
+	var version int
+	if v, found := m["version"]; found {
+		i, ok := v.(int64)
+		if !ok || i < 0 {
+			return nil, 0, fmt.Errorf("invalid version %v", v)
+		}
+		version = int(i)
+	}
+
+	if version > configVersion {
+		return nil, 0, fmt.Errorf("config version %d is newer than the supported version %d", version, configVersion)
+	}
+
+	for i := version; i < configVersion; i++ {
+		migrations[i](m)
+	}
+	m["version"] = configVersion
+
+	return m, version, nil
+}
+
+// config holds all settings. The keys in the config file are the same as
+// the flag names.
+type config struct {
+	// Version is the version of the config format; see configVersion.
+	Version int `toml:"version"`
+
+	// Profile names the preset the other settings are applied on top of.
+	Profile string `toml:"profile"`
+
+	Embedded      string   `toml:"embedded"`
+	StructLits    bool     `toml:"structlits"`
+	MapLits       bool     `toml:"maplits"`
+	Mode          string   `toml:"mode"`
+	Size          string   `toml:"size"`
+	Minimal       bool     `toml:"minimal"`
+	Insert        bool     `toml:"insert"`
+	Only          []string `toml:"only"`
+	StructFields  bool     `toml:"structfields"`
+	ExportedTypes bool     `toml:"exportedtypes"`
+	Errors        string   `toml:"errors"`
+	PinFlags      bool     `toml:"pinflags"`
+	Pairs         []string `toml:"pairs"`
+	Normalize     bool     `toml:"normalize"`
+	Banners       bool     `toml:"banners"`
+	Outline       bool     `toml:"outline"`
+	GRPC          bool     `toml:"grpc"`
+	Align         bool     `toml:"align"`
+	FuncVars      bool     `toml:"funcvars"`
+
+	// Prefixes ignored when comparing names, e.g. Get and Set.
+	Prefixes []string `toml:"prefixes"`
+
+	// Exclude holds file patterns to skip; see excluded.
+	Exclude []string `toml:"exclude"`
+
+	// MaxMovesPercent is the share of the declarations in a file, in
+	// percent, above which -w leaves it as is unless -force is set. 0
+	// disables the check.
+	MaxMovesPercent int `toml:"max-moves-percent"`
+
+	// Lang is the Go language version of the source; see gorder.Options.
+	Lang string `toml:"lang"`
+
+	// Stubs is the placement of function stubs; see gorder.StubsKeep.
+	Stubs string `toml:"stubs"`
+
+	// Floating is the placement of free-floating comments; see
+	// gorder.FloatingNext.
+	Floating string `toml:"floating"`
+
+	// SelfCheck sorts each file twice to check the second pass changes
+	// nothing.
+	SelfCheck bool `toml:"self-check"`
+
+	// Imports groups, sorts and dedupes the imports, with those matching
+	// Local grouped last.
+	Imports bool     `toml:"imports"`
+	Local   []string `toml:"local"`
+
+	// Gofmt formats the sorted source with gofmt, and Gofumpt with gofumpt.
+	Gofmt   bool `toml:"gofmt"`
+	Gofumpt bool `toml:"gofumpt"`
+
+	// GeneratedBy, if set, is what keeps the declarations in order, e.g.
+	// go generate, stated in a comment at the top of the files; see stamp.
+	GeneratedBy string `toml:"generated-by"`
+
+	// changed holds the changed lines of the file with -hunks.
+	changed []gorder.LineRange
+
+	// SkipGenerated leaves generated files as they are; see isGenerated.
+	SkipGenerated bool `toml:"skip-generated"`
+
+	// Cgo also reorders cgo files; see isCgo.
+	Cgo bool `toml:"cgo"`
+
+	// LineDirectives is what to do with files with //line directives, one
+	// of lineDirectivesSkip or lineDirectivesStrip.
+	LineDirectives string `toml:"linedirectives"`
+
+	// Directives enables the //gorder: comment directives.
+	Directives bool `toml:"directives"`
+
+	// Constructors holds the name prefixes of constructor functions.
+	Constructors []string `toml:"constructors"`
+	CtorReturn   bool     `toml:"ctorreturn"`
+
+	// Enable and Disable hold rule IDs; see rules.
+	Enable  []string `toml:"enable"`
+	Disable []string `toml:"disable"`
+
+	// Weights holds the section weights. Lower weights sort higher up.
+	Weights gorder.Weights `toml:"weights"`
+}
+
+func defaultConfig() config {
+	return config{
+		Version:  configVersion,
+		Embedded: gorder.EmbeddedFirst,
+		Mode:     gorder.ModeDefault,
+		Size:     gorder.SizeNone,
+		Errors:   gorder.ErrorsNone,
+		Prefixes: append([]string(nil), gorder.DefaultPrefixes...),
+		Weights:  gorder.DefaultWeights,
+
+		Constructors: []string{"New"},
+		Directives:   true,
+
+		Floating: gorder.FloatingNext,
+		Stubs:    gorder.StubsKeep,
+
+		MaxMovesPercent: 60,
+		LineDirectives:  lineDirectivesSkip,
+	}
+}
+
+// registerFlags registers a flag for each setting in c on fs.
+func registerFlags(fs *flag.FlagSet, c *config) {
+	fs.StringVar(&c.Profile, "profile", c.Profile, "preset of settings to start from: "+strings.Join(profileNames(), ", "))
+	fs.StringVar(&c.Embedded, "embedded", c.Embedded, "placement of embedded interface and struct members: first, last or mixed")
+	fs.BoolVar(&c.StructLits, "structlits", c.StructLits, "sort the fields of keyed struct literals whose values are free of side effects")
+	fs.BoolVar(&c.MapLits, "maplits", c.MapLits, "sort the entries of map literals with constant keys")
+	fs.StringVar(&c.Mode, "mode", c.Mode, "placement strategy: default, caller to move helpers below their first caller, alpha to sort by name only, or godoc to sort like go doc")
+	fs.StringVar(&c.Size, "size", c.Size, "order otherwise equally named declarations by line count: none, asc or desc")
+	fs.BoolVar(&c.Minimal, "minimal", c.Minimal, "keep the longest already ordered run of declarations in place and only move the others")
+	fs.BoolVar(&c.Insert, "insert", c.Insert, "only move declarations added since the git HEAD version of the file")
+	fs.Var((*listFlag)(&c.Only), "only", "comma separated list of declaration kinds to sort: func, type, const, var, interface, struct (default all)")
+	fs.BoolVar(&c.StructFields, "structfields", c.StructFields, "sort the fields of struct types")
+	fs.BoolVar(&c.ExportedTypes, "exportedtypes", c.ExportedTypes, "place exported types and their methods before unexported types")
+	fs.StringVar(&c.Errors, "errors", c.Errors, "group sentinel error vars and error types in a section at the top or bottom: none, top or bottom")
+	fs.BoolVar(&c.PinFlags, "pinflags", c.PinFlags, "in package main, place flag var declarations right before func main")
+	fs.Var((*listFlag)(&c.Pairs), "pairs", "comma separated groups of colon separated method names kept adjacent and in order on the same receiver, e.g. Open:Close; -enable=lifecycle-pairs uses "+gorder.DefaultPairs)
+	fs.BoolVar(&c.Normalize, "normalize", c.Normalize, "separate top-level declarations by exactly one blank line")
+	fs.BoolVar(&c.Banners, "banners", c.Banners, "maintain banner comments above each section of declarations")
+	fs.BoolVar(&c.Outline, "outline", c.Outline, "maintain an outline comment of the types and functions after the imports")
+	fs.BoolVar(&c.GRPC, "grpc", c.GRPC, "order gRPC handler methods as in the generated service interface")
+	fs.BoolVar(&c.Align, "align", c.Align, "align the declaration order of platform variant files, e.g. foo_linux.go and foo_windows.go")
+	fs.BoolVar(&c.FuncVars, "funcvars", c.FuncVars, "sort package level vars holding functions as functions")
+	fs.Var((*listFlag)(&c.Prefixes), "prefixes", "comma separated name prefixes ignored when comparing names")
+	fs.Var((*listFlag)(&c.Exclude), "exclude", "comma separated file patterns to skip")
+	fs.IntVar(&c.MaxMovesPercent, "max-moves-percent", c.MaxMovesPercent, "with -w, leave files where more than this percentage of the declarations would move as is, unless -force is set; 0 to disable")
+	fs.StringVar(&c.Lang, "lang", c.Lang, "Go language version the source targets, e.g. go1.21; files using newer features are left as is with an error")
+	fs.StringVar(&c.Stubs, "stubs", c.Stubs, "placement of functions without a body, implemented in assembly, and of //go:linkname declarations: keep to leave them in place, group to move them to the end of the file, or sort")
+	fs.StringVar(&c.Floating, "floating", c.Floating, "placement of comments separated by a blank line from the declaration below: next to move them with it, previous to move them with the one above, or keep to leave them in place")
+	fs.BoolVar(&c.SelfCheck, "self-check", c.SelfCheck, "sort each file twice and fail if the second pass changes it further")
+	fs.StringVar(&c.GeneratedBy, "generated-by", c.GeneratedBy, "stamp the files with a comment at the top saying their declarations are ordered by this, e.g. \"go generate\"")
+	fs.BoolVar(&c.Imports, "imports", c.Imports, "group, sort and dedupe the imports: standard library, others, then those matching -local")
+	fs.Var((*listFlag)(&c.Local), "local", "comma separated import path prefixes grouped last with -imports, as goimports -local")
+	fs.BoolVar(&c.Gofmt, "gofmt", c.Gofmt, "format the sorted source with gofmt")
+	fs.BoolVar(&c.Gofumpt, "gofumpt", c.Gofumpt, "format the sorted source with gofumpt, using -lang as the language version")
+	fs.BoolVar(&c.SkipGenerated, "skip-generated", c.SkipGenerated, "leave generated files, marked with a // Code generated ... DO NOT EDIT. comment, as they are")
+	fs.BoolVar(&c.Cgo, "cgo", c.Cgo, "also reorder cgo files, importing \"C\", keeping the preamble and //export comments intact")
+	fs.StringVar(&c.LineDirectives, "linedirectives", c.LineDirectives, "files with //line directives, e.g. from goyacc: skip to leave them as is, or strip to remove the directives and reorder")
+	fs.BoolVar(&c.Directives, "directives", c.Directives, "enable //gorder: comment directives")
+	fs.Var((*listFlag)(&c.Constructors), "constructors", "comma separated name prefixes of constructor functions, e.g. New,Make,Must; New also matches new")
+	fs.BoolVar(&c.CtorReturn, "ctorreturn", c.CtorReturn, "also treat functions returning a type declared in the file, or a pointer to one, as constructors")
+	fs.Var((*listFlag)(&c.Enable), "enable", "comma separated rule IDs to enable: "+strings.Join(ruleIDs(), ", "))
+	fs.Var((*listFlag)(&c.Disable), "disable", "comma separated rule IDs to disable, applied after -enable")
+}
+
+// configResolver resolves the configuration for a directory. The config
+// files found in the directory and its parents, up to the module root, are
+// applied on top of the profile from the top down, so a subdirectory's file
+// overrides the keys it sets and inherits the rest; tables such as weights
+// are merged key by key, lists are replaced. Flags set in the environment
+// override all files, flags set on the command line override the environment.
+type configResolver struct {
+	fs *flag.FlagSet
+
+	// The config the flags in fs write to.
+	c *config
+
+	// The flags set on the command line.
+	flags map[string]string
+
+	// The flags set in the environment; see envFlags.
+	env map[string]string
+
+	// filename is an explicit config file to use instead of discovery.
+	filename string
+
+	// disabled ignores all config files.
+	disabled bool
+
+	// strict rejects unknown keys in config files.
+	strict bool
+
+	cache map[string]config
+
+	// The config files with unknown keys warned about.
+	warned map[string]bool
+}
+
+// newConfigResolver creates a resolver for the flags in fs, which must be
+// parsed and write to c, and env, the flags set in the environment.
+func newConfigResolver(fs *flag.FlagSet, c *config, env map[string]string, filename string, disabled bool) *configResolver {
+	r := &configResolver{
+		fs:       fs,
+		c:        c,
+		flags:    make(map[string]string),
+		env:      env,
+		filename: filename,
+		disabled: disabled,
+		cache:    make(map[string]config),
+		warned:   make(map[string]bool),
+	}
+	fs.Visit(func(f *flag.Flag) {
+		r.flags[f.Name] = f.Value.String()
+	})
+	return r
+}
+
+// resolve returns the configuration for files in dir.
+func (r *configResolver) resolve(dir string) (config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return config{}, err
+	}
+
+	if c, ok := r.cache[dir]; ok {
+		return c, nil
+	}
+
+	filenames, err := r.files(dir)
+	if err != nil {
+		return config{}, err
+	}
+
+	files := make([][]byte, len(filenames))
+	for i, filename := range filenames {
+		if files[i], err = readConfig(filename); err != nil {
+			return config{}, err
+		}
+	}
+
+	load := func() error {
+		for i, b := range files {
+			d := toml.NewDecoder(bytes.NewReader(b))
+			d.DisallowUnknownFields()
+			if err := d.Decode(r.c); err != nil {
+				var serr *toml.StrictMissingError
+				if !errors.As(err, &serr) {
+					return fmt.Errorf("%s: %s", filenames[i], err)
+				}
+				if r.strict {
+					return fmt.Errorf("%s: unknown keys:\n%s", filenames[i], serr.String())
+				}
+				if !r.warned[filenames[i]] {
+					r.warned[filenames[i]] = true
+					fmt.Fprintf(os.Stderr, "warning: %s: ignoring unknown keys, run gorder config validate for details\n", filenames[i])
+				}
+			}
+		}
+		return nil
+	}
+
+	// The profile goes below the files, so find it first.
+	*r.c = defaultConfig()
+	if err := load(); err != nil {
+		return config{}, err
+	}
+	profile := r.c.Profile
+	if name, ok := r.env["profile"]; ok {
+		profile = name
+	}
+	if name, ok := r.flags["profile"]; ok {
+		profile = name
+	}
+
+	*r.c = defaultConfig()
+	if err := applyProfile(r.c, profile); err != nil {
+		return config{}, err
+	}
+	if err := load(); err != nil {
+		return config{}, err
+	}
+
+	for name, value := range r.env {
+		if err := r.fs.Set(name, value); err != nil {
+			return config{}, fmt.Errorf("%s: %s", envName(name), err)
+		}
+	}
+	for name, value := range r.flags {
+		if err := r.fs.Set(name, value); err != nil {
+			return config{}, err
+		}
+	}
+
+	c := *r.c
+	r.cache[dir] = c
+
+	return c, nil
+}
+
+// envPrefix is the prefix of the environment variables setting flags, e.g.
+// GORDER_MODE=caller or GORDER_NO_CONFIG=true.
+const envPrefix = "GORDER_"
+
+// envName returns the environment variable for the named flag.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// envFlags returns the values of the flags in fs set in the environment but
+// not on the command line.
+func envFlags(fs *flag.FlagSet) map[string]string {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	env := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] {
+			return
+		}
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			env[f.Name] = v
+		}
+	})
+	return env
+}
+
+// readConfig reads the config file filename, upgraded to configVersion.
+func readConfig(filename string) ([]byte, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	m, version, err := migrateConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if version == configVersion {
+		return b, nil
+	}
+
+	return toml.Marshal(m)
+}
+
+// files returns the config files applied to dir, outermost first.
+func (r *configResolver) files(dir string) ([]string, error) {
+	switch {
+	case r.disabled:
+		return nil, nil
+	case r.filename != "":
+		return []string{r.filename}, nil
+	default:
+		return findConfigs(dir)
+	}
+}
+
+// findConfigs returns the config files in dir and its parents, outermost
+// first, stopping at the first directory holding a go.mod.
+func findConfigs(dir string) ([]string, error) {
+	var filenames []string
+	for {
+		filename := filepath.Join(dir, configName)
+		if _, err := os.Stat(filename); err == nil {
+			filenames = append([]string{filename}, filenames...)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return filenames, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return filenames, nil
+		}
+		dir = parent
+	}
+}
+
+// options validates c and converts it to gorder.Options.
+func (c config) options() (gorder.Options, error) {
+	opts := gorder.Options{
+		Embedded:       c.Embedded,
+		StructLiterals: c.StructLits,
+		MapLiterals:    c.MapLits,
+		Mode:           c.Mode,
+		Size:           c.Size,
+		Minimal:        c.Minimal,
+		InsertOnly:     c.Insert,
+		StructFields:   c.StructFields,
+
+		ExportedTypesFirst: c.ExportedTypes,
+		Errors:             c.Errors,
+		FlagsNearMain:      c.PinFlags,
+		Pairs:              gorder.ParsePairs(strings.Join(c.Pairs, ",")),
+		NormalizeSpace:     c.Normalize,
+		Banners:            c.Banners,
+		Outline:            c.Outline,
+		GRPC:               c.GRPC,
+		FuncVars:           c.FuncVars,
+		Weights:            c.Weights,
+		Prefixes:           c.Prefixes,
+		Directives:         c.Directives,
+		Constructors:       c.Constructors,
+		CtorReturn:         c.CtorReturn,
+		SelfCheck:          c.SelfCheck,
+		FloatingComments:   c.Floating,
+		Stubs:              c.Stubs,
+		Lang:               c.Lang,
+		ChangedLines:       c.changed,
+		Imports:            c.Imports,
+		LocalPrefixes:      c.Local,
+
+		StripLineDirectives: c.LineDirectives == lineDirectivesStrip,
+	}
+
+	switch {
+	case c.Gofumpt:
+		lang := c.Lang
+		opts.Format = func(src []byte) ([]byte, error) {
+			return gofumpt.Source(src, gofumpt.Options{LangVersion: lang})
+		}
+	case c.Gofmt:
+		opts.Format = gofmt.Source
+	}
+
+	if c.GeneratedBy != "" {
+		if strings.ContainsAny(c.GeneratedBy, "\r\n") {
+			return opts, fmt.Errorf("invalid -generated-by value %q, must be a single line", c.GeneratedBy)
+		}
+		format, by := opts.Format, c.GeneratedBy
+		opts.Format = func(src []byte) ([]byte, error) {
+			if format != nil {
+				var err error
+				if src, err = format(src); err != nil {
+					return nil, err
+				}
+			}
+			return stamp(src, by), nil
+		}
+	}
+
+	switch c.LineDirectives {
+	case lineDirectivesSkip, lineDirectivesStrip:
+	default:
+		return opts, fmt.Errorf("invalid -linedirectives value %q", c.LineDirectives)
+	}
+
+	var err error
+	if opts.Kinds, err = gorder.ParseKinds(strings.Join(c.Only, ",")); err != nil {
+		return opts, err
+	}
+
+	if err := applyRules(&opts, c.Enable, c.Disable); err != nil {
+		return opts, err
+	}
+
+	return opts, opts.Validate()
+}
+
+// excluded reports whether filename matches one of the exclude patterns,
+// either by its base name or its full path.
+func (c config) excluded(filename string) (bool, error) {
+	slashed := filepath.ToSlash(filename)
+	for _, pattern := range c.Exclude {
+		for _, name := range []string{filepath.Base(filename), slashed} {
+			matched, err := filepath.Match(pattern, name)
+			if err != nil {
+				return false, fmt.Errorf("invalid exclude pattern %q: %s", pattern, err)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// isGenerated reports whether src is a generated file, with the comment
+// described in https://go.dev/s/generatedcode before the package clause.
+func isGenerated(src []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false
+	}
+	return ast.IsGenerated(f)
+}
+
+// isCgo reports whether src is a cgo file, importing "C".
+func isCgo(src []byte) bool {
+	if !bytes.Contains(src, []byte(`"C"`)) {
+		return false
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return false
+	}
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+// The -linedirectives values.
+const (
+	lineDirectivesSkip  = "skip"
+	lineDirectivesStrip = "strip"
+)
+
+// skipReason returns the reason files with the source src are left as is
+// with c, or "" if they are not.
+func skipReason(src []byte, c config) string {
+	switch {
+	case c.SkipGenerated && isGenerated(src):
+		return skippedGenerated
+	case !c.Cgo && isCgo(src):
+		return skippedCgo
+	case c.LineDirectives == lineDirectivesSkip && gorder.HasLineDirectives(src):
+		return skippedLines
+	}
+	return ""
+}
+
+// listFlag is a comma separated list flag.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
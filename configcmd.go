@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// weightDocs describes the keys of the weights table.
+var weightDocs = map[string]string{
+	"errorsbottom": "the error section with -errors=bottom",
+	"func":         "unexported functions",
+	"type":         "types and their methods",
+	"constructor":  "unexported constructors, e.g. newFoo",
+	"exported":     "exported functions, New functions go right above",
+	"main":         "func main",
+	"flags":        "flag vars with -pinflags",
+	"errorstop":    "the error section with -errors=top",
+}
+
+// runConfig runs the config subcommand given by args.
+func runConfig(r *configResolver, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing config command, expected init, validate or migrate")
+	}
+
+	switch args[0] {
+	case "init":
+		return configInit(args[1:])
+	case "validate":
+		return configValidate(r, args[1:])
+	case "migrate":
+		return configMigrate(args[1:])
+	default:
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+}
+
+// configInit writes the default configuration to the file named in args, or
+// to configName in the current directory. It does not overwrite files. The
+// keys are commented out, so a profile set in the file is not undone by
+// them.
+func configInit(args []string) error {
+	filename := configName
+	switch len(args) {
+	case 0:
+	case 1:
+		filename = args[0]
+	default:
+		return errors.New("usage: gorder config init [filename]")
+	}
+
+	var b bytes.Buffer
+	if err := writeConfig(&b, defaultConfig(), true); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// configValidate resolves the configuration for the path in args, or the
+// current directory, reports any problems and prints the effective settings.
+func configValidate(r *configResolver, args []string) error {
+	path := "."
+	switch len(args) {
+	case 0:
+	case 1:
+		path = args[0]
+	default:
+		return errors.New("usage: gorder config validate [path]")
+	}
+
+	dir := path
+	if fi, err := os.Stat(path); err != nil {
+		return err
+	} else if !fi.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	filenames, err := r.files(dir)
+	if err != nil {
+		return err
+	}
+
+	r.strict = true
+	c, err := r.resolve(dir)
+	if err != nil {
+		return err
+	}
+
+	problems := configProblems(c)
+	for _, p := range problems {
+		log.Print(p)
+	}
+
+	if len(filenames) == 0 {
+		fmt.Println("# No config files.")
+	}
+	for _, filename := range filenames {
+		fmt.Printf("# From %s\n", filename)
+	}
+	if err := writeConfig(os.Stdout, c, false); err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s)", len(problems))
+	}
+	return nil
+}
+
+// configProblems returns the invalid values and conflicting settings in c.
+func configProblems(c config) []string {
+	var problems []string
+
+	if _, err := c.options(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	for _, pattern := range c.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid exclude pattern %q: %s", pattern, err))
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, group := range gorder.ParsePairs(strings.Join(c.Pairs, ",")) {
+		for _, name := range group {
+			if seen[name] {
+				problems = append(problems, fmt.Sprintf("method %q is in more than one pair group", name))
+			}
+			seen[name] = true
+		}
+	}
+
+	enabled := make(map[string]bool)
+	for _, id := range c.Enable {
+		enabled[id] = true
+	}
+	for _, id := range c.Disable {
+		if enabled[id] {
+			problems = append(problems, fmt.Sprintf("rule %q is both enabled and disabled", id))
+		}
+	}
+
+	if c.Mode == gorder.ModeAlpha && c.Weights != gorder.DefaultWeights {
+		problems = append(problems, "weights are ignored with mode alpha")
+	}
+
+	return problems
+}
+
+// configMigrate upgrades the config file named in args, or configName in the
+// current directory, to configVersion. Comments are not kept.
+func configMigrate(args []string) error {
+	filename := configName
+	switch len(args) {
+	case 0:
+	case 1:
+		filename = args[0]
+	default:
+		return errors.New("usage: gorder config migrate [filename]")
+	}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	m, version, err := migrateConfig(b)
+	if err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	if version == configVersion {
+		fmt.Printf("%s is already at version %d\n", filename, configVersion)
+		return nil
+	}
+
+	if b, err = toml.Marshal(m); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, b, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Migrated %s from version %d to %d\n", filename, version, configVersion)
+	return nil
+}
+
+// writeConfig writes c to w in TOML, with each key documented by the usage
+// of its flag. With commented, the keys but version are commented out.
+func writeConfig(w io.Writer, c config, commented bool) error {
+	b, err := toml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	registerFlags(fs, &c)
+
+	fmt.Fprintf(w, "# gorder configuration. The keys are the flag names; flags given on the\n")
+	fmt.Fprintf(w, "# command line override this file.\n")
+	if commented {
+		fmt.Fprintf(w, "# The keys are commented out with their default values; uncomment them\n")
+		fmt.Fprintf(w, "# to set them, over the profile if any.\n")
+	}
+
+	var table string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			table = strings.Trim(line, "[]")
+			fmt.Fprintf(w, "\n# The section weights. Lower weights sort higher up.\n%s\n", line)
+			continue
+		}
+
+		key := strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+
+		var doc string
+		if table == "" {
+			if f := fs.Lookup(key); f != nil {
+				doc = f.Usage
+			} else if key == "version" {
+				doc = "the version of the config format, upgrade with gorder config migrate"
+			}
+		} else {
+			doc = weightDocs[key]
+		}
+
+		if table == "" {
+			fmt.Fprintln(w)
+		}
+		if doc != "" {
+			fmt.Fprintf(w, "# %s.\n", firstToUpper(doc))
+		}
+		if commented && !(table == "" && key == "version") {
+			line = "# " + line
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	return nil
+}
+
+func firstToUpper(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
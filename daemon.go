@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// runDaemon runs the daemon subcommand, which serves reorder requests on a
+// Unix socket until interrupted, keeping the configs resolved in memory.
+// See daemonRequest for the protocol.
+func runDaemon(r *configResolver, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	socket := fs.String("socket", defaultSocket(), "Unix socket to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *cacheDir != "" {
+		var err error
+		if cache, err = newFileCache(*cacheDir); err != nil {
+			return err
+		}
+	}
+
+	// Remove the socket of a daemon no longer running.
+	if conn, err := net.Dial("unix", *socket); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s: a daemon is already listening", *socket)
+	}
+	os.Remove(*socket)
+
+	l, err := net.Listen("unix", *socket)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	d := &daemon{resolver: r, stamps: make(map[string]string)}
+
+	var wg sync.WaitGroup
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.serve(ctx, conn)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// defaultSocket returns the socket the daemon listens on by default, one
+// per user.
+func defaultSocket() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, fmt.Sprintf("gorder-%d.sock", os.Getuid()))
+}
+
+// daemonRequest is a request to the daemon: the content of the file
+// File, which need not be saved, to reorder with the config applying to
+// it, and with Positions the line mappings of the declarations. A
+// connection takes any number of requests, as JSON values, and gets a
+// daemonResponse for each, in order, as a line of JSON.
+type daemonRequest struct {
+	File      string `json:"file"`
+	Src       string `json:"src"`
+	Positions bool   `json:"positions,omitempty"`
+}
+
+// daemonResponse is the response to a daemonRequest: the report on the
+// file and the reordered source, the same as the source if the file is in
+// order, skipped or failed.
+type daemonResponse struct {
+	report
+	Src string `json:"src"`
+}
+
+// daemon serves the reorder requests.
+type daemon struct {
+	// mu guards resolver and stamps.
+	mu       sync.Mutex
+	resolver *configResolver
+
+	// stamps holds, per directory, the config files last resolved for it
+	// with their modification times.
+	stamps map[string]string
+}
+
+// serve answers the requests on conn until it is closed or ctx is done.
+func (d *daemon) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req daemonRequest
+		if err := dec.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				enc.Encode(daemonResponse{report: report{Error: newReportError(err)}})
+			}
+			return
+		}
+		if err := enc.Encode(d.handle(ctx, req)); err != nil {
+			return
+		}
+	}
+}
+
+// handle reorders the source in req, as handleFile does for the files on
+// disk.
+func (d *daemon) handle(ctx context.Context, req daemonRequest) daemonResponse {
+	resp := daemonResponse{report: report{File: req.File}, Src: req.Src}
+
+	fail := func(err error) daemonResponse {
+		resp.Error = newReportError(err)
+		return resp
+	}
+
+	if req.File == "" {
+		return fail(errors.New("missing file"))
+	}
+
+	c, err := d.config(req.File)
+	if err != nil {
+		return fail(err)
+	}
+	excluded, err := c.excluded(req.File)
+	if err != nil {
+		return fail(err)
+	}
+	if excluded {
+		resp.Skipped = skippedExcluded
+		return resp
+	}
+	return reorderSource(ctx, req, c)
+}
+
+// reorderSource reorders the source in req with c.
+func reorderSource(ctx context.Context, req daemonRequest, c config) daemonResponse {
+	resp := daemonResponse{report: report{File: req.File}, Src: req.Src}
+	src := []byte(req.Src)
+
+	fail := func(err error) daemonResponse {
+		resp.Error = newReportError(err)
+		return resp
+	}
+
+	if reason := skipReason(src, c); reason != "" {
+		resp.Skipped = reason
+		return resp
+	}
+
+	var key string
+	if cache != nil && cacheable(c) {
+		var err error
+		if key, err = cache.key(src, c); err != nil {
+			return fail(err)
+		}
+		if cache.ordered(key) {
+			return resp
+		}
+	}
+
+	opts, err := fileOptions(req.File, src, c)
+	if err != nil {
+		return fail(err)
+	}
+	r, err := gorder.ReorderContext(ctx, req.File, src, opts)
+	if err != nil {
+		return fail(fmt.Errorf("%s: %w", req.File, err))
+	}
+
+	resp.line = firstChangedLine(src, r.Src)
+	resp.Changed = resp.line > 0
+	resp.Moves = r.Moves
+	resp.Src = string(r.Src)
+	if req.Positions && resp.Changed {
+		if resp.Positions, err = lineMappings(src, r.Src); err != nil {
+			return fail(err)
+		}
+	}
+	if key != "" && !resp.Changed {
+		cache.markOrdered(key)
+	}
+	return resp
+}
+
+// config returns the config for filename, resolving it again if the config
+// files applied to its directory changed since last time.
+func (d *daemon) config(filename string) (config, error) {
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return config{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	files, err := d.resolver.files(dir)
+	if err != nil {
+		return config{}, err
+	}
+	var stamp strings.Builder
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return config{}, err
+		}
+		fmt.Fprintf(&stamp, "%s %d\n", f, fi.ModTime().UnixNano())
+	}
+	if d.stamps[dir] != stamp.String() {
+		delete(d.resolver.cache, dir)
+		d.stamps[dir] = stamp.String()
+	}
+
+	return d.resolver.resolve(dir)
+}
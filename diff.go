@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// edit is a line of a diff, kept (' '), deleted ('-') or inserted ('+').
+type edit struct {
+	op   byte
+	text string
+
+	// The number of lines in a and b before this one.
+	a, b int
+}
+
+// lineDiff returns the edits turning the lines a into b, using Myers'
+// algorithm.
+func lineDiff(a, b []string) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace[d] holds v for k in [-d-1, d+1] before step d.
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string) []edit {
+	var edits []edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{op: ' ', text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				edits = append(edits, edit{op: '+', text: b[y]})
+			} else {
+				x--
+				edits = append(edits, edit{op: '-', text: a[x]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	// Reverse and number the edits.
+	var ai, bi int
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	for i := range edits {
+		edits[i].a, edits[i].b = ai, bi
+		if edits[i].op != '+' {
+			ai++
+		}
+		if edits[i].op != '-' {
+			bi++
+		}
+	}
+
+	return edits
+}
+
+// splitLines splits s into lines, keeping the line endings.
+func splitLines(s []byte) []string {
+	lines := strings.SplitAfter(string(s), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// gitDiff returns the diff of a and b in the git format, which git apply
+// accepts, for the file filename.
+func gitDiff(filename string, a, b []byte) []byte {
+	name := filepath.ToSlash(filename)
+	if filepath.IsAbs(filename) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, filename); err == nil {
+				name = filepath.ToSlash(rel)
+			}
+		}
+	}
+	d := unifiedDiff("a/"+name, "b/"+name, a, b, false, nil, nil)
+	if d == nil {
+		return nil
+	}
+	return append([]byte("diff --git a/"+name+" b/"+name+"\n"), d...)
+}
+
+// hunk is a run of changed lines: the Len lines from line Start, counted
+// from 1, are replaced by Text. With Len 0, Text is inserted before Start.
+type hunk struct {
+	Start, Len int
+	Text       string
+}
+
+// changedHunks returns the runs of changed lines turning a into b.
+func changedHunks(a, b []byte) []hunk {
+	var (
+		hunks []hunk
+		cur   *hunk
+	)
+	for _, e := range lineDiff(splitLines(a), splitLines(b)) {
+		if e.op == ' ' {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			hunks = append(hunks, hunk{Start: e.a + 1})
+			cur = &hunks[len(hunks)-1]
+		}
+		if e.op == '-' {
+			cur.Len++
+		} else {
+			cur.Text += e.text
+		}
+	}
+	return hunks
+}
+
+// moveMarks returns the first lines of the declarations moved, before and
+// after.
+func moveMarks(moves []gorder.Move) (oldMarks, newMarks map[int]bool) {
+	oldMarks, newMarks = make(map[int]bool), make(map[int]bool)
+	for _, m := range moves {
+		oldMarks[m.OldLine] = true
+		newMarks[m.NewLine] = true
+	}
+	return oldMarks, newMarks
+}
+
+// diffContext is the number of unchanged lines around each hunk.
+const diffContext = 3
+
+// unifiedDiff returns the unified diff of a and b, named aName and bName, or
+// nil if they are equal. The lines in oldMarks and newMarks, the first lines
+// of the declarations moved, are highlighted with color.
+func unifiedDiff(aName, bName string, a, b []byte, c colorizer, oldMarks, newMarks map[int]bool) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+
+	al, bl := splitLines(a), splitLines(b)
+	edits := lineDiff(al, bl)
+
+	var buf bytes.Buffer
+	buf.WriteString(c.header("--- " + aName))
+	buf.WriteString(c.header("+++ " + bName))
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk to the last change that is at most 2*diffContext
+		// unchanged lines from the previous one.
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		stop := end + diffContext
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		writeHunk(&buf, edits[start:stop], c, oldMarks, newMarks)
+		i = stop
+	}
+
+	return buf.Bytes()
+}
+
+func writeHunk(buf *bytes.Buffer, hunk []edit, c colorizer, oldMarks, newMarks map[int]bool) {
+	var aLen, bLen int
+	for _, e := range hunk {
+		if e.op != '+' {
+			aLen++
+		}
+		if e.op != '-' {
+			bLen++
+		}
+	}
+	aStart, bStart := hunk[0].a, hunk[0].b
+	if aLen > 0 {
+		aStart++
+	}
+	if bLen > 0 {
+		bStart++
+	}
+	buf.WriteString(c.hunk(fmt.Sprintf("@@ -%d,%d +%d,%d @@", aStart, aLen, bStart, bLen)))
+
+	for _, e := range hunk {
+		text := strings.TrimSuffix(e.text, "\n")
+		line := string(e.op) + text
+		switch {
+		case e.op == '-':
+			buf.WriteString(c.deleted(line, oldMarks[e.a+1]))
+		case e.op == '+':
+			buf.WriteString(c.inserted(line, newMarks[e.b+1]))
+		default:
+			buf.WriteString(line + "\n")
+		}
+		if !strings.HasSuffix(e.text, "\n") {
+			buf.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
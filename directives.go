@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// directiveConfig at the top of a file adjusts settings for that file. The
+// names are the flag names, e.g. //gorder:config mode=caller
+// structfields=true.
+const directiveConfig = "config"
+
+// applyConfigDirective applies settings on the form "mode=caller size=asc"
+// to c.
+func applyConfigDirective(s string, c *config) error {
+	var settings [][2]string
+	for _, kv := range strings.Fields(s) {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("invalid setting %q, expected name=value", kv)
+		}
+		settings = append(settings, [2]string{name, value})
+	}
+	return applySettings(settings, c)
+}
+
+// applySettings applies the settings, pairs of a flag name and value, to c.
+// A profile goes below the other settings.
+func applySettings(settings [][2]string, c *config) error {
+	fs := flag.NewFlagSet(gorder.DirectivePrefix+directiveConfig, flag.ContinueOnError)
+	registerFlags(fs, c)
+
+	for _, kv := range settings {
+		if kv[0] == "profile" {
+			if err := applyProfile(c, kv[1]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kv := range settings {
+		if kv[0] == "profile" {
+			continue
+		}
+		if fs.Lookup(kv[0]) == nil {
+			return fmt.Errorf("unknown setting %q", kv[0])
+		}
+		if err := fs.Set(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("invalid setting %q: %s", kv[0]+"="+kv[1], err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"io"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// The -format values.
+const (
+	formatText       = "text"
+	formatJSON       = "json"
+	formatSARIF      = "sarif"
+	formatGitHub     = "github"
+	formatCheckstyle = "checkstyle"
+	formatExplain    = "explain"
+	formatRDJSON     = "rdjson"
+)
+
+// report is the result of processing a file, as printed with -format=json.
+type report struct {
+	File    string        `json:"file"`
+	Module  string        `json:"module,omitempty"`
+	Changed bool          `json:"changed"`
+	Moves   []gorder.Move `json:"moves,omitempty"`
+	Error   *reportError  `json:"error,omitempty"`
+
+	// Positions maps the lines of the declarations at other lines, with
+	// -positions, or when asked for in a daemon request.
+	Positions []lineMapping `json:"positions,omitempty"`
+
+	// Skipped is the reason the file was skipped, if it was.
+	Skipped string `json:"skipped,omitempty"`
+
+	// The first line that changed.
+	line int
+
+	// The content hashes before and after, for -manifest.
+	before, after string
+
+	// The changed lines, for -format=rdjson.
+	hunks []hunk
+
+	// The diff printed with -d or written with -patch, or the explanation
+	// printed with -format=explain.
+	text []byte
+}
+
+// The reasons files are skipped.
+const (
+	skippedGenerated = "generated"
+	skippedCgo       = "cgo"
+	skippedLines     = "linedirectives"
+	skippedExcluded  = "excluded" // By the daemon, the command does not report them.
+)
+
+// reportError is an error with the position it applies to, if known.
+type reportError struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+func newReportError(err error) *reportError {
+	e := &reportError{Message: err.Error()}
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		e.Line, e.Column = list[0].Pos.Line, list[0].Pos.Column
+	}
+	return e
+}
+
+// orderRule is the rule of the findings in files that changed without
+// moving any declarations, e.g. with sorted struct fields.
+const orderRule = "order"
+
+// finding is an ordering violation in a file.
+type finding struct {
+	rule    string
+	message string
+	line    int
+}
+
+// findings returns the ordering violations in r, one per declaration moved.
+func (r report) findings() []finding {
+	if !r.Changed {
+		return nil
+	}
+	if len(r.Moves) == 0 {
+		return []finding{{rule: orderRule, message: "declarations are out of order", line: r.line}}
+	}
+	var findings []finding
+	for _, m := range r.Moves {
+		name := m.Name
+		if m.Receiver != "" {
+			name = m.Receiver + "." + name
+		}
+		findings = append(findings, finding{
+			rule:    moveRule(m),
+			message: fmt.Sprintf("%s %s is out of order", m.Kind, name),
+			line:    m.OldLine,
+		})
+	}
+	return findings
+}
+
+// moveRule returns the ID of the rule in rules that m violates.
+func moveRule(m gorder.Move) string {
+	switch m.Kind {
+	case "func", "method":
+		return "func-order"
+	case "type":
+		return "type-order"
+	case "const":
+		return "const-order"
+	case "var":
+		return "var-order"
+	default:
+		return orderRule
+	}
+}
+
+// explainMoves returns a line with the reason for each declaration moved in
+// r, the result of reordering src in the file filename with the config c.
+// Declarations only passing others in the same section are sorted by name.
+func explainMoves(filename string, src []byte, r gorder.Result, c config) ([]byte, error) {
+	if len(r.Moves) == 0 {
+		return nil, nil
+	}
+	opts, err := fileOptions(filename, src, c)
+	if err != nil {
+		return nil, err
+	}
+	before, err := gorder.Explain(src, opts)
+	if err != nil {
+		return nil, err
+	}
+	after, err := gorder.Explain(r.Src, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	type key [3]string
+	oldIndex := make(map[key]int)
+	for i, e := range before {
+		oldIndex[key{e.Kind, e.Receiver, e.Name}] = i
+	}
+	newIndex := make(map[key]int)
+	for i, e := range after {
+		newIndex[key{e.Kind, e.Receiver, e.Name}] = i
+	}
+
+	var b bytes.Buffer
+	for _, m := range r.Moves {
+		k := key{m.Kind, m.Receiver, m.Name}
+		i, ok := newIndex[k]
+		if !ok {
+			continue
+		}
+		e := after[i]
+
+		// Whether all the declarations it passed, or that passed it, are in
+		// the same section.
+		sameSection := true
+		for j, x := range after {
+			xk := key{x.Kind, x.Receiver, x.Name}
+			if (oldIndex[xk] < oldIndex[k]) != (j < i) && (x.Weight != e.Weight || x.Receiver != e.Receiver) {
+				sameSection = false
+				break
+			}
+		}
+
+		reason := e.Reason()
+		if sameSection && e.Weight != -1 {
+			reason = fmt.Sprintf("%s %s sorted by name", m.Kind, m.Name)
+			if m.Receiver != "" {
+				reason = fmt.Sprintf("method %s.%s sorted by name among the methods of %s", m.Receiver, m.Name, m.Receiver)
+			} else if e.Section != "" {
+				reason += " within the " + e.Section + " section"
+			}
+			if e.Prefix != "" {
+				reason += ", ignoring the prefix " + e.Prefix
+			}
+		}
+		fmt.Fprintf(&b, "%s:%d: %s\n", filename, m.OldLine, reason)
+	}
+	return b.Bytes(), nil
+}
+
+// ruleDoc returns the description of the rule id.
+func ruleDoc(id string) string {
+	if r, ok := rules[id]; ok {
+		return r.doc
+	}
+	return "keep declarations in order"
+}
+
+// printReports writes reports to w in format.
+func printReports(w io.Writer, format string, reports []report) error {
+	switch format {
+	case formatJSON:
+		if reports == nil {
+			reports = []report{}
+		}
+		return writeJSON(w, reports)
+	case formatSARIF:
+		return writeJSON(w, sarifLog(reports))
+	case formatGitHub:
+		return writeGitHub(w, reports)
+	case formatCheckstyle:
+		return writeCheckstyle(w, reports)
+	case formatRDJSON:
+		return writeJSON(w, rdjsonResult(reports))
+	case formatExplain:
+		for _, r := range reports {
+			if _, err := w.Write(r.text); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid -format value %q", format)
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
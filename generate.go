@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"os"
+)
+
+// goGenerate reports whether gorder runs from go generate, which sets
+// GOFILE and GOPACKAGE, e.g. from //go:generate gorder -w $GOFILE.
+func goGenerate() bool {
+	return os.Getenv("GOFILE") != "" && os.Getenv("GOPACKAGE") != ""
+}
+
+// stampPrefix starts the comment written with -generated-by.
+const stampPrefix = "// Declarations ordered by "
+
+// stamp returns src with the comment saying its declarations are ordered by
+// by as the first line, followed by a blank line, replacing the comment
+// there.
+func stamp(src []byte, by string) []byte {
+	line := stampPrefix + by + ".\n"
+	if bytes.HasPrefix(src, []byte(stampPrefix)) {
+		if i := bytes.IndexByte(src, '\n'); i >= 0 {
+			return append([]byte(line), src[i+1:]...)
+		}
+	}
+	return append([]byte(line+"\n"), src...)
+}
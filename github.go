@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeGitHub writes reports to w as GitHub Actions workflow commands, an
+// ::error annotation per finding and error.
+func writeGitHub(w io.Writer, reports []report) error {
+	for _, r := range reports {
+		for _, f := range r.findings() {
+			if err := githubCommand(w, r.File, f.line, 0, "gorder "+f.rule, f.message); err != nil {
+				return err
+			}
+		}
+		if r.Error != nil {
+			if err := githubCommand(w, r.File, r.Error.Line, r.Error.Column, "gorder", r.Error.Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func githubCommand(w io.Writer, file string, line, col int, title, message string) error {
+	props := []string{"file=" + githubEscapeProperty(file)}
+	if line > 0 {
+		props = append(props, fmt.Sprintf("line=%d", line))
+	}
+	if col > 0 {
+		props = append(props, fmt.Sprintf("col=%d", col))
+	}
+	props = append(props, "title="+githubEscapeProperty(title))
+	_, err := fmt.Fprintf(w, "::error %s::%s\n", strings.Join(props, ","), githubEscapeData(message))
+	return err
+}
+
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func githubEscapeData(s string) string {
+	return githubDataEscaper.Replace(s)
+}
+
+func githubEscapeProperty(s string) string {
+	return githubPropertyEscaper.Replace(s)
+}
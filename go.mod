@@ -1,6 +1,17 @@
 module github.com/bep/gorder
 
+go 1.23.0
+
+require (
+	github.com/dave/dst v0.27.3
+	github.com/golangci/plugin-module-register v0.1.2
+	github.com/pelletier/go-toml/v2 v2.2.4
+	golang.org/x/mod v0.25.0
+	golang.org/x/tools v0.34.0
+	mvdan.cc/gofumpt v0.8.0
+)
+
 require (
-	github.com/dave/dst v0.23.1
-	github.com/stretchr/testify v1.3.0 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	golang.org/x/sync v0.15.0 // indirect
 )
@@ -0,0 +1,130 @@
+// Package golangci registers gorder as a golangci-lint module plugin. Build
+// it into golangci-lint with golangci-lint custom and this in
+// .custom-gcl.yml:
+//
+//	plugins:
+//	  - module: github.com/bep/gorder
+//	    import: github.com/bep/gorder/golangci
+//	    version: latest
+//
+// The settings, in linters.settings.custom.gorder.settings, take the keys
+// of the gorder config files for the options they hold.
+package golangci
+
+import (
+	"strings"
+
+	"github.com/bep/gorder/analyzer"
+	"github.com/bep/gorder/gorder"
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+)
+
+func init() {
+	register.Plugin("gorder", New)
+}
+
+// Settings holds the plugin settings. The options not set keep their
+// default, as in gorder.DefaultOptions.
+type Settings struct {
+	Mode          string   `json:"mode"`
+	Embedded      string   `json:"embedded"`
+	Errors        string   `json:"errors"`
+	Size          string   `json:"size"`
+	Stubs         string   `json:"stubs"`
+	Floating      string   `json:"floating"`
+	Lang          string   `json:"lang"`
+	Only          []string `json:"only"`
+	Prefixes      []string `json:"prefixes"`
+	Constructors  []string `json:"constructors"`
+	StructFields  *bool    `json:"structfields"`
+	StructLits    *bool    `json:"structlits"`
+	MapLits       *bool    `json:"maplits"`
+	ExportedTypes *bool    `json:"exportedtypes"`
+	FuncVars      *bool    `json:"funcvars"`
+	CtorReturn    *bool    `json:"ctorreturn"`
+	Minimal       *bool    `json:"minimal"`
+	Directives    *bool    `json:"directives"`
+}
+
+// New creates the plugin with the golangci-lint settings conf.
+func New(conf any) (register.LinterPlugin, error) {
+	s, err := register.DecodeSettings[Settings](conf)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := s.options()
+	if err != nil {
+		return nil, err
+	}
+	return plugin{opts: opts}, nil
+}
+
+func (s Settings) options() (gorder.Options, error) {
+	opts := gorder.DefaultOptions()
+
+	for _, v := range []struct {
+		s   string
+		opt *string
+	}{
+		{s.Mode, &opts.Mode},
+		{s.Embedded, &opts.Embedded},
+		{s.Errors, &opts.Errors},
+		{s.Size, &opts.Size},
+		{s.Stubs, &opts.Stubs},
+		{s.Floating, &opts.FloatingComments},
+		{s.Lang, &opts.Lang},
+	} {
+		if v.s != "" {
+			*v.opt = v.s
+		}
+	}
+
+	for _, v := range []struct {
+		b   *bool
+		opt *bool
+	}{
+		{s.StructFields, &opts.StructFields},
+		{s.StructLits, &opts.StructLiterals},
+		{s.MapLits, &opts.MapLiterals},
+		{s.ExportedTypes, &opts.ExportedTypesFirst},
+		{s.FuncVars, &opts.FuncVars},
+		{s.CtorReturn, &opts.CtorReturn},
+		{s.Minimal, &opts.Minimal},
+		{s.Directives, &opts.Directives},
+	} {
+		if v.b != nil {
+			*v.opt = *v.b
+		}
+	}
+
+	if s.Only != nil {
+		kinds, err := gorder.ParseKinds(strings.Join(s.Only, ","))
+		if err != nil {
+			return gorder.Options{}, err
+		}
+		opts.Kinds = kinds
+	}
+	if s.Prefixes != nil {
+		opts.Prefixes = s.Prefixes
+	}
+	if s.Constructors != nil {
+		opts.Constructors = s.Constructors
+	}
+
+	return opts, opts.Validate()
+}
+
+type plugin struct {
+	opts gorder.Options
+}
+
+func (p plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{analyzer.New(p.opts)}, nil
+}
+
+// GetLoadMode returns register.LoadModeSyntax, gorder needs no type
+// information.
+func (p plugin) GetLoadMode() string {
+	return register.LoadModeSyntax
+}
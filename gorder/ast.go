@@ -0,0 +1,77 @@
+package gorder
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"go/token"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+)
+
+// SortAST sorts file, parsed with comments into fset, and returns the result
+// as a new file in a new file set. The positions in fset are not valid for
+// the new file.
+func SortAST(fset *token.FileSet, file *ast.File, opts Options) (*token.FileSet, *ast.File, error) {
+	f, err := sortAST(fset, file, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := decorator.NewRestorer()
+	af, err := r.RestoreFile(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return r.Fset, af, nil
+}
+
+// ProcessAST sorts file, parsed with comments into fset, and returns the
+// printed result.
+func ProcessAST(fset *token.FileSet, file *ast.File, opts Options) ([]byte, error) {
+	f, err := sortAST(fset, file, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := decorator.Fprint(&b, f); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func sortAST(fset *token.FileSet, file *ast.File, opts Options) (*dst.File, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	dec := decorator.NewDecorator(fset)
+	f, err := dec.DecorateFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines map[dst.Decl]int
+	if opts.Size != SizeNone {
+		lines = declLines(fset, dec.Ast.Nodes, f.Decls)
+	}
+
+	var changed map[dst.Decl]bool
+	if opts.ChangedLines != nil {
+		changed = changedDecls(fset, dec.Ast.Nodes, f.Decls, opts.ChangedLines)
+	}
+
+	var filename string
+	if tf := fset.File(file.Pos()); tf != nil {
+		filename = tf.Name()
+	}
+
+	if err := sortFile(context.Background(), filename, f, lines, changed, opts); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
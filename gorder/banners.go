@@ -0,0 +1,72 @@
+package gorder
+
+import (
+	"regexp"
+
+	"github.com/dave/dst"
+)
+
+// bannerRe matches the banner comments maintained by gorder, e.g.
+//
+//	// ---- Types ----
+var bannerRe = regexp.MustCompile(`^// ---- .+ ----$`)
+
+// sectionTitle returns the banner title of the section for the given
+// weight.
+func sectionTitle(weight int, w Weights) string {
+	switch {
+	case weight < 0:
+		return "Constants and variables"
+	case weight == w.ErrorsTop, weight == w.ErrorsBottom:
+		return "Errors"
+	case weight == w.Flags:
+		return "Flags"
+	case weight == w.Main:
+		return "Main"
+	case weight == w.Exported, weight == w.Exported-1:
+		return "Exported functions"
+	case weight == w.Constructor:
+		return "Constructors"
+	case weight == w.Type:
+		return "Types"
+	default:
+		return "Functions"
+	}
+}
+
+// addBanners inserts a banner comment above the first declaration of each
+// section in decls.
+func addBanners(decls []dst.Decl, ranker Ranker, w Weights) {
+	prev := ""
+	for _, d := range decls {
+		if preserveOrder(d) {
+			continue
+		}
+		_, weight := ranker.Key(d)
+		title := sectionTitle(weight, w)
+		if title == prev {
+			continue
+		}
+		prev = title
+		d.Decorations().Start.Prepend("// ---- "+title+" ----", "\n")
+	}
+}
+
+// removeBanners removes the banner comments added by addBanners, along with
+// the blank line following them.
+func removeBanners(decls []dst.Decl) {
+	for _, d := range decls {
+		decs := d.Decorations()
+		var start []string
+		for i := 0; i < len(decs.Start); i++ {
+			if bannerRe.MatchString(decs.Start[i]) {
+				if i+1 < len(decs.Start) && decs.Start[i+1] == "\n" {
+					i++
+				}
+				continue
+			}
+			start = append(start, decs.Start[i])
+		}
+		decs.Start.Replace(start...)
+	}
+}
@@ -0,0 +1,80 @@
+package gorder
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"strings"
+)
+
+// errCgoChanged guards against sorting detaching the preamble of a cgo file
+// from its import "C", or an //export comment from its function, which
+// would break the build or change what is exported to C.
+var errCgoChanged = errors.New("the cgo preamble or //export comments would change, leaving the file as is")
+
+// cgoComments returns the cgo preamble of src, the comment directly above
+// import "C", and the doc comments holding //export lines by function name.
+// It returns ok false if src does not import "C".
+func cgoComments(src []byte) (preamble string, exports map[string]string, ok bool) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return "", nil, false
+	}
+	text := func(g *ast.CommentGroup) string {
+		return string(src[fset.Position(g.Pos()).Offset:fset.Position(g.End()).Offset])
+	}
+
+	exports = make(map[string]string)
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if s := spec.(*ast.ImportSpec); s.Path.Value == `"C"` {
+					ok = true
+					doc := s.Doc
+					if doc == nil && len(d.Specs) == 1 {
+						doc = d.Doc
+					}
+					if doc != nil {
+						preamble = text(doc)
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Doc == nil || d.Recv != nil {
+				continue
+			}
+			for _, c := range d.Doc.List {
+				if strings.HasPrefix(c.Text, "//export ") {
+					exports[d.Name.Name] = text(d.Doc)
+					break
+				}
+			}
+		}
+	}
+	return preamble, exports, ok
+}
+
+// checkCgo returns errCgoChanged if src is a cgo file and its preamble or
+// //export comments differ in out.
+func checkCgo(src, out []byte) error {
+	if !bytes.Contains(src, []byte(`"C"`)) {
+		return nil
+	}
+	preamble, exports, ok := cgoComments(src)
+	if !ok {
+		return nil
+	}
+	outPreamble, outExports, _ := cgoComments(out)
+	if preamble != outPreamble || !maps.Equal(exports, outExports) {
+		return errCgoChanged
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package gorder
+
+import (
+	"bytes"
+	"errors"
+	"go/build/constraint"
+	"slices"
+)
+
+// buildConstraints returns the //go:build and // +build lines above the
+// package clause in src, each followed by a newline and, if so in src, a
+// blank line, which the go command requires after the last constraint.
+func buildConstraints(src []byte) []string {
+	var constraints []string
+	lines := bytes.Split(src, []byte("\n"))
+	for i, line := range lines {
+		s := string(bytes.TrimSpace(line))
+		if bytes.HasPrefix(line, []byte("package ")) {
+			break
+		}
+		if constraint.IsGoBuild(s) || constraint.IsPlusBuild(s) {
+			if i+1 < len(lines) && len(bytes.TrimSpace(lines[i+1])) == 0 {
+				s += "\n"
+			}
+			constraints = append(constraints, s+"\n")
+		}
+	}
+	return constraints
+}
+
+// errConstraintsChanged guards against sorting detaching or swallowing the
+// build constraints of a file, which would silently change what it builds
+// for.
+var errConstraintsChanged = errors.New("the build constraints would change, leaving the file as is")
+
+// checkBuildConstraints returns errConstraintsChanged if the build
+// constraints of src and out differ.
+func checkBuildConstraints(src, out []byte) error {
+	if !bytes.Contains(src, []byte("build")) {
+		return nil
+	}
+	if !slices.Equal(buildConstraints(src), buildConstraints(out)) {
+		return errConstraintsChanged
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package gorder
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// sortSource sorts src with opts, failing t on errors.
+func sortSource(t *testing.T, src string, opts Options) string {
+	t.Helper()
+	r, err := Reorder("x.go", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(r.Src)
+}
+
+func TestBuildConstraintsKept(t *testing.T) {
+	for _, test := range []struct {
+		name, src, want string
+	}{
+		{
+			"go:build and +build",
+			`//go:build linux
+// +build linux
+
+package p
+
+func b() {}
+
+func a() {}
+`,
+			`//go:build linux
+// +build linux
+
+package p
+
+func a() {}
+
+func b() {}
+`,
+		},
+		{
+			"below a license header",
+			`// Copyright 2024 The Authors.
+
+//go:build !windows
+
+// Package p does things.
+package p
+
+func b() {}
+
+func a() {}
+`,
+			`// Copyright 2024 The Authors.
+
+//go:build !windows
+
+// Package p does things.
+package p
+
+func a() {}
+
+func b() {}
+`,
+		},
+		{
+			"first declaration with a doc comment moving",
+			`//go:build go1.18
+
+package p
+
+// Zed comes last.
+func Zed() {}
+
+func Alpha() {}
+`,
+			`//go:build go1.18
+
+package p
+
+func Alpha() {}
+
+// Zed comes last.
+func Zed() {}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sortSource(t, test.src, DefaultOptions()); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBuildConstraints(t *testing.T) {
+	src := []byte("// Header.\n\n//go:build linux\n// +build linux\n\npackage p\n\n//go:build ignored\n")
+	want := []string{"//go:build linux\n", "// +build linux\n\n"}
+	if got := buildConstraints(src); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckBuildConstraints(t *testing.T) {
+	src := []byte("//go:build linux\n\npackage p\n")
+	for _, test := range []struct {
+		name string
+		out  string
+		want error
+	}{
+		{"kept", "//go:build linux\n\npackage p\n", nil},
+		{"dropped", "package p\n", errConstraintsChanged},
+		{"blank line swallowed", "//go:build linux\npackage p\n", errConstraintsChanged},
+		{"moved below the package clause", "package p\n\n//go:build linux\n", errConstraintsChanged},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := checkBuildConstraints(src, []byte(test.out)); !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
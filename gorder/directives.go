@@ -0,0 +1,183 @@
+package gorder
+
+import (
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+)
+
+// Directives are line comments on the form //gorder:name [args], without a
+// space after the slashes, like other Go tool directives.
+const (
+	DirectivePrefix = "//gorder:"
+
+	// directiveKeep on a type declaration leaves the order of its interface
+	// methods or struct fields as written.
+	directiveKeep = "keep"
+
+	// directiveWeights at the top of a file overrides section weights for
+	// that file, e.g. //gorder:weights func=10 type=100.
+	directiveWeights = "weights"
+
+	// directiveOrder gives the exact order of the named declarations. On a
+	// type it names methods, e.g. //gorder:order Open,Read,Write,Close; at
+	// the top of a file it names top-level declarations. The named
+	// declarations go first, the others follow using the normal rules.
+	directiveOrder = "order"
+
+	// directiveGroup with the argument begin or end marks a run of
+	// declarations that move as one unit, keeping their internal order.
+	directiveGroup = "group"
+)
+
+// findDirective returns the arguments of the first directive with the given
+// name in decs and whether it was found.
+func findDirective(decs dst.Decorations, name string) (string, bool) {
+	for _, d := range decs {
+		if !strings.HasPrefix(d, DirectivePrefix) {
+			continue
+		}
+		s := strings.TrimPrefix(d, DirectivePrefix)
+		if s == name {
+			return "", true
+		}
+		if strings.HasPrefix(s, name+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(s, name)), true
+		}
+	}
+	return "", false
+}
+
+func hasDirective(decs dst.Decorations, name string) bool {
+	_, found := findDirective(decs, name)
+	return found
+}
+
+// detachFileDirectives moves a comment block holding gorder directives from
+// above the first declaration to below the package clause, so it stays at the
+// top of the file when the declaration moves. The block must be separated
+// from the declaration's own comments by a blank line.
+func detachFileDirectives(file *dst.File) {
+	if len(file.Decls) == 0 {
+		return
+	}
+
+	start := file.Decls[0].Decorations().Start
+	seen := false
+	for i, dec := range start {
+		if strings.HasPrefix(dec, DirectivePrefix) {
+			seen = true
+		}
+		if dec == "\n" && seen {
+			file.Decs.Name.Append("\n")
+			file.Decs.Name.Append(start[:i]...)
+			file.Decls[0].Decorations().Start.Replace(start[i+1:]...)
+			return
+		}
+	}
+}
+
+// FileDirective returns the arguments of the file level directive with the
+// given name in the Go source src and whether it was found. See fileDirective.
+func FileDirective(src []byte, name string) (string, bool, error) {
+	file, err := decorator.Parse(src)
+	if err != nil {
+		return "", false, err
+	}
+	detachFileDirectives(file)
+	args, found := fileDirective(file, name)
+	return args, found, nil
+}
+
+// fileDirective looks for a file level directive in the comments above the
+// package clause or directly below it.
+func fileDirective(file *dst.File, name string) (string, bool) {
+	if args, found := findDirective(file.Decs.Start, name); found {
+		return args, true
+	}
+	if args, found := findDirective(file.Decs.Name, name); found {
+		return args, true
+	}
+	if len(file.Decls) > 0 {
+		return findDirective(file.Decls[0].Decorations().Start, name)
+	}
+	return "", false
+}
+
+// parseWeights applies weight overrides on the form "func=10 type=100" to w.
+func parseWeights(s string, w Weights) (Weights, error) {
+	fields := map[string]*int{
+		"func":        &w.Func,
+		"type":        &w.Type,
+		"constructor": &w.Constructor,
+		"exported":    &w.Exported,
+		"main":        &w.Main,
+		"flags":       &w.Flags,
+	}
+
+	for _, kv := range strings.Fields(s) {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return w, fmt.Errorf("invalid weight %q, expected name=value", kv)
+		}
+		v, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return w, fmt.Errorf("invalid weight %q: %s", kv, err)
+		}
+		if parts[0] == "errors" {
+			w.ErrorsTop, w.ErrorsBottom = v, v
+			continue
+		}
+		p, ok := fields[parts[0]]
+		if !ok {
+			return w, fmt.Errorf("unknown weight %q", parts[0])
+		}
+		*p = v
+	}
+
+	return w, nil
+}
+
+// applyOrderDirectives records the orders given by //gorder:order
+// directives in file in info, overriding any order already there.
+func applyOrderDirectives(file *dst.File, info *fileInfo) {
+	if args, found := fileDirective(file, directiveOrder); found {
+		info.declOrder = splitList(args)
+	}
+
+	for _, d := range file.Decls {
+		g, ok := d.(*dst.GenDecl)
+		if !ok || g.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range g.Specs {
+			ts := spec.(*dst.TypeSpec)
+			args, found := findDirective(ts.Decs.Start, directiveOrder)
+			if !found && len(g.Specs) == 1 {
+				args, found = findDirective(g.Decs.Start, directiveOrder)
+			}
+			if !found {
+				continue
+			}
+			if info.methodOrder == nil {
+				info.methodOrder = make(map[string][]string)
+			}
+			info.methodOrder[ts.Name.Name] = splitList(args)
+		}
+	}
+}
+
+// splitList splits a comma separated list, dropping empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
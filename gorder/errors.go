@@ -0,0 +1,75 @@
+package gorder
+
+import (
+	"go/token"
+
+	"github.com/dave/dst"
+)
+
+// isSentinelErrorDecl reports whether d is a var declaration where every
+// value is created with errors.New or fmt.Errorf, e.g.
+//
+//	var ErrNotFound = errors.New("not found")
+func isSentinelErrorDecl(d *dst.GenDecl) bool {
+	if d.Tok != token.VAR || len(d.Specs) == 0 {
+		return false
+	}
+
+	for _, spec := range d.Specs {
+		vs := spec.(*dst.ValueSpec)
+		if len(vs.Values) == 0 || len(vs.Values) != len(vs.Names) {
+			return false
+		}
+		for _, v := range vs.Values {
+			if !isErrorConstructor(v) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isErrorConstructor(e dst.Expr) bool {
+	call, ok := e.(*dst.CallExpr)
+	if !ok {
+		return false
+	}
+
+	switch fun := call.Fun.(type) {
+	case *dst.SelectorExpr:
+		pkg, ok := fun.X.(*dst.Ident)
+		if !ok {
+			return false
+		}
+		return pkg.Name == "errors" && fun.Sel.Name == "New" ||
+			pkg.Name == "fmt" && fun.Sel.Name == "Errorf"
+	case *dst.Ident:
+		// Dot imports and resolved identifiers.
+		return fun.Path == "errors" && fun.Name == "New" ||
+			fun.Path == "fmt" && fun.Name == "Errorf"
+	}
+
+	return false
+}
+
+// errorTypeNames returns the names of the types in decls that have an
+// Error() string method declared in decls.
+func errorTypeNames(decls []dst.Decl) map[string]bool {
+	names := make(map[string]bool)
+	for _, d := range decls {
+		f, ok := d.(*dst.FuncDecl)
+		if !ok || f.Recv == nil || f.Name.Name != "Error" {
+			continue
+		}
+		if len(f.Type.Params.List) != 0 || f.Type.Results == nil || len(f.Type.Results.List) != 1 {
+			continue
+		}
+		res := f.Type.Results.List[0]
+		if id, ok := res.Type.(*dst.Ident); !ok || id.Name != "string" || len(res.Names) > 1 {
+			continue
+		}
+		names[fieldListName(f.Recv)] = true
+	}
+	return names
+}
@@ -0,0 +1,175 @@
+package gorder
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+)
+
+// Explanation describes how a top-level declaration is ranked.
+type Explanation struct {
+	// Name, Kind and Receiver are as in Move.
+	Name     string
+	Kind     string
+	Receiver string
+
+	// Key is the sort name given by the Sorter, e.g. T.Method for methods.
+	Key string
+
+	// Weight is the section weight. It is -1 for declarations without an
+	// opinionated position.
+	Weight int
+
+	// Section is the Weights field matching Weight, e.g. exported, or empty
+	// if none does.
+	Section string
+
+	// Constructor reports whether the declaration is ranked as a
+	// constructor.
+	Constructor bool
+
+	// Adjustment is added to the weight of names in the same section, e.g.
+	// -2 for exported names. Only set with the HeuristicSorter.
+	Adjustment int
+
+	// Prefix is the name prefix, one of Options.Prefixes, that is ignored
+	// when comparing, and SortName the name compared. Only set with the
+	// HeuristicSorter.
+	Prefix   string
+	SortName string
+}
+
+// Explain returns how each top-level declaration in the Go source src is
+// ranked with opts, in source order.
+func Explain(src []byte, opts Options) ([]Explanation, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	file, err := parse(decorator.NewDecorator(token.NewFileSet()), src)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Name.Name != "main" {
+		opts.FlagsNearMain = false
+	}
+
+	if opts.Directives {
+		detachFileDirectives(file)
+
+		if args, found := fileDirective(file, directiveWeights); found {
+			if opts.Weights, err = parseWeights(args, opts.Weights); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ranker := opts.sorter().Ranker(file.Decls, opts)
+
+	var explanations []Explanation
+	for _, d := range file.Decls {
+		if preserveOrder(d) {
+			continue
+		}
+		explanations = append(explanations, explain(ranker, d, opts))
+	}
+
+	return explanations, nil
+}
+
+func explain(ranker Ranker, d dst.Decl, opts Options) Explanation {
+	kind, recv, name := declName(d)
+	key, weight := ranker.Key(d)
+	e := Explanation{
+		Name:     name,
+		Kind:     kind,
+		Receiver: recv,
+		Key:      key,
+		Weight:   weight,
+		Section:  sectionName(opts.Weights, weight),
+	}
+	if kind == "func" && weight != -1 && (weight == opts.Weights.Exported-1 || weight == opts.Weights.Constructor) {
+		e.Constructor = true
+	}
+	if r, ok := ranker.(*declRanker); ok && key != "" {
+		_, s := splitOnDot(key)
+		e.Adjustment = weightAdjustment(s, r.isConstructor(key))
+		e.Prefix, e.SortName = trimCommonPrefix(s, opts.Prefixes)
+	}
+	return e
+}
+
+// sectionName returns the name of the field in w with the given weight.
+// Exported constructors are one above the exported section.
+func sectionName(w Weights, weight int) string {
+	if weight == -1 {
+		return ""
+	}
+	for _, s := range []struct {
+		name   string
+		weight int
+	}{
+		{"main", w.Main},
+		{"flags", w.Flags},
+		{"errorstop", w.ErrorsTop},
+		{"exported", w.Exported},
+		{"constructor", w.Constructor},
+		{"type", w.Type},
+		{"func", w.Func},
+		{"errorsbottom", w.ErrorsBottom},
+	} {
+		if weight == s.weight {
+			return s.name
+		}
+	}
+	if weight == w.Exported-1 {
+		return "exported"
+	}
+	return ""
+}
+
+// Reason returns a one-line rationale for the position of the declaration,
+// e.g. "method T.M grouped under type T".
+func (e Explanation) Reason() string {
+	recv, name := splitOnDot(e.Key)
+	if e.Kind == "method" && recv != "" {
+		return fmt.Sprintf("method %s.%s grouped under type %s", e.Receiver, e.Name, e.Receiver)
+	}
+	if e.Kind == "func" && strings.HasPrefix(name, godocMarker) {
+		return fmt.Sprintf("func %s grouped under type %s, which it returns", e.Name, recv)
+	}
+
+	what := e.Kind + " " + e.Name
+	switch e.Section {
+	case "main":
+		return "func main placed above the other functions"
+	case "flags":
+		return fmt.Sprintf("flag %s placed right before func main", what)
+	case "errorstop":
+		return fmt.Sprintf("error %s grouped in the errors section at the top", what)
+	case "errorsbottom":
+		return fmt.Sprintf("error %s grouped in the errors section at the bottom", what)
+	case "exported":
+		if e.Constructor {
+			return fmt.Sprintf("exported constructor %s placed first among the exported functions", e.Name)
+		}
+		return fmt.Sprintf("exported %s promoted above unexported helpers", what)
+	case "constructor":
+		return fmt.Sprintf("constructor %s placed above the other unexported functions", e.Name)
+	case "type":
+		if e.Kind == "type" {
+			return fmt.Sprintf("%s placed among the types, followed by its methods", what)
+		}
+		return fmt.Sprintf("%s placed among the types", what)
+	case "func":
+		return fmt.Sprintf("unexported %s placed below the exported functions and types", what)
+	}
+	if e.Weight == -1 {
+		return fmt.Sprintf("%s kept in order with the other unsorted declarations", what)
+	}
+	return fmt.Sprintf("%s sorted by name", what)
+}
@@ -0,0 +1,120 @@
+package gorder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// exprString renders the type expression x as Go source, without comments
+// and with the parameter and field lists abbreviated, to order the members of
+// interfaces and structs that are not plain type names.
+func exprString(x dst.Expr) string {
+	var b strings.Builder
+	writeExpr(&b, x)
+	return b.String()
+}
+
+func writeExpr(b *strings.Builder, x dst.Expr) {
+	switch v := x.(type) {
+	case nil:
+	case *dst.Ident:
+		b.WriteString(v.Name)
+	case *dst.BasicLit:
+		b.WriteString(v.Value)
+	case *dst.SelectorExpr:
+		writeExpr(b, v.X)
+		b.WriteByte('.')
+		b.WriteString(v.Sel.Name)
+	case *dst.StarExpr:
+		b.WriteByte('*')
+		writeExpr(b, v.X)
+	case *dst.ParenExpr:
+		b.WriteByte('(')
+		writeExpr(b, v.X)
+		b.WriteByte(')')
+	case *dst.UnaryExpr:
+		b.WriteString(v.Op.String())
+		writeExpr(b, v.X)
+	case *dst.BinaryExpr:
+		writeExpr(b, v.X)
+		b.WriteString(" " + v.Op.String() + " ")
+		writeExpr(b, v.Y)
+	case *dst.IndexExpr:
+		writeExpr(b, v.X)
+		b.WriteByte('[')
+		writeExpr(b, v.Index)
+		b.WriteByte(']')
+	case *dst.IndexListExpr:
+		writeExpr(b, v.X)
+		b.WriteByte('[')
+		for i, index := range v.Indices {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeExpr(b, index)
+		}
+		b.WriteByte(']')
+	case *dst.ArrayType:
+		b.WriteByte('[')
+		writeExpr(b, v.Len)
+		b.WriteByte(']')
+		writeExpr(b, v.Elt)
+	case *dst.Ellipsis:
+		b.WriteString("...")
+		writeExpr(b, v.Elt)
+	case *dst.MapType:
+		b.WriteString("map[")
+		writeExpr(b, v.Key)
+		b.WriteByte(']')
+		writeExpr(b, v.Value)
+	case *dst.ChanType:
+		switch v.Dir {
+		case dst.SEND:
+			b.WriteString("chan<- ")
+		case dst.RECV:
+			b.WriteString("<-chan ")
+		default:
+			b.WriteString("chan ")
+		}
+		writeExpr(b, v.Value)
+	case *dst.FuncType:
+		b.WriteString("func")
+		writeFieldList(b, v.Params, "(", ")")
+		if v.Results != nil && len(v.Results.List) > 0 {
+			b.WriteByte(' ')
+			writeFieldList(b, v.Results, "(", ")")
+		}
+	case *dst.InterfaceType:
+		b.WriteString("interface")
+		writeFieldList(b, v.Methods, "{", "}")
+	case *dst.StructType:
+		b.WriteString("struct")
+		writeFieldList(b, v.Fields, "{", "}")
+	default:
+		fmt.Fprintf(b, "%T", x)
+	}
+}
+
+func writeFieldList(b *strings.Builder, list *dst.FieldList, open, close string) {
+	b.WriteString(open)
+	if list != nil {
+		for i, f := range list.List {
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			for j, name := range f.Names {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(name.Name)
+			}
+			if len(f.Names) > 0 {
+				b.WriteByte(' ')
+			}
+			writeExpr(b, f.Type)
+		}
+	}
+	b.WriteString(close)
+}
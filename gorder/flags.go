@@ -0,0 +1,39 @@
+package gorder
+
+import (
+	"go/token"
+
+	"github.com/dave/dst"
+)
+
+// isFlagDecl reports whether d is a var declaration where every value is a
+// flag registration from the standard flag package, e.g.
+//
+//	var write = flag.Bool("w", false, "write result to file")
+func isFlagDecl(d *dst.GenDecl) bool {
+	if d.Tok != token.VAR || len(d.Specs) == 0 {
+		return false
+	}
+
+	for _, spec := range d.Specs {
+		vs := spec.(*dst.ValueSpec)
+		if len(vs.Values) == 0 {
+			return false
+		}
+		for _, v := range vs.Values {
+			call, ok := v.(*dst.CallExpr)
+			if !ok {
+				return false
+			}
+			sel, ok := call.Fun.(*dst.SelectorExpr)
+			if !ok {
+				return false
+			}
+			if pkg, ok := sel.X.(*dst.Ident); !ok || pkg.Name != "flag" {
+				return false
+			}
+		}
+	}
+
+	return true
+}
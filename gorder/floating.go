@@ -0,0 +1,130 @@
+package gorder
+
+import (
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// Placement of free-floating comments, separated by a blank line from the
+// declaration below them.
+const (
+	// FloatingNext moves them with the declaration below.
+	FloatingNext = "next"
+
+	// FloatingPrevious moves them with the declaration above.
+	FloatingPrevious = "previous"
+
+	// FloatingKeep leaves them at their position in the file, above the
+	// declaration sorted into the place of the one below them.
+	FloatingKeep = "keep"
+)
+
+// floatingComments returns the free-floating comment block at the start of
+// the decorations of d, up to and including the last blank line before its
+// doc comment, or nil if it has none. Blocks holding directives are not
+// free-floating.
+func floatingComments(d dst.Decl) []string {
+	start := d.Decorations().Start
+	end := 0
+	for i, c := range start {
+		if c == "\n" {
+			end = i + 1
+		}
+	}
+
+	var comment bool
+	for _, c := range start[:end] {
+		if c == "\n" {
+			continue
+		}
+		if strings.HasPrefix(c, DirectivePrefix) || isToolDirective(c) {
+			return nil
+		}
+		comment = true
+	}
+	if !comment {
+		return nil
+	}
+	return append([]string(nil), start[:end]...)
+}
+
+// detachFloating applies the policy to the free-floating comments above
+// decls before sorting. With FloatingKeep, it returns the detached blocks by
+// position, to restore with attachFloating after sorting.
+func detachFloating(decls []dst.Decl, policy string) map[int][]string {
+	if policy != FloatingPrevious && policy != FloatingKeep {
+		return nil
+	}
+
+	kept := make(map[int][]string)
+	for i := 1; i < len(decls); i++ {
+		block := floatingComments(decls[i])
+		if block == nil {
+			continue
+		}
+		start := &decls[i].Decorations().Start
+		start.Replace((*start)[len(block):]...)
+		if policy == FloatingKeep {
+			kept[i] = block
+			continue
+		}
+		end := &decls[i-1].Decorations().End
+		end.Append("\n")
+		end.Append(block...)
+	}
+	return kept
+}
+
+// attachFloating puts the blocks detached by detachFloating back above the
+// declarations now at their positions.
+func attachFloating(decls []dst.Decl, kept map[int][]string) {
+	for i, block := range kept {
+		if i < len(decls) {
+			start := &decls[i].Decorations().Start
+			start.Replace(append(block, *start...)...)
+		}
+	}
+}
+
+// detachTrailingComments removes the comments below the last of decls, at
+// the end of the file, such as editor modelines or code commented out, and
+// returns them to restore with attachTrailingComments after sorting. A
+// comment on the same line as the end of the declaration stays with it.
+func detachTrailingComments(decls []dst.Decl) []string {
+	if len(decls) == 0 {
+		return nil
+	}
+	end := &decls[len(decls)-1].Decorations().End
+	for i, c := range *end {
+		// The decorations after a newline, or a line comment, are on the
+		// lines below.
+		split := -1
+		switch {
+		case c == "\n":
+			split = i
+		case strings.HasPrefix(c, "//"):
+			split = i + 1
+		}
+		if split == -1 || split == len(*end) {
+			continue
+		}
+		trailing := append([]string(nil), (*end)[split:]...)
+		if trailing[0] != "\n" {
+			trailing = append([]string{"\n"}, trailing...)
+		}
+		end.Replace((*end)[:split]...)
+		return trailing
+	}
+	return nil
+}
+
+// attachTrailingComments puts the comments detached by
+// detachTrailingComments back at the end of the file, below the last of
+// decls.
+func attachTrailingComments(decls []dst.Decl, trailing []string) {
+	if len(decls) == 0 || len(trailing) == 0 {
+		return
+	}
+	decls[len(decls)-1].Decorations().End.Append(trailing...)
+}
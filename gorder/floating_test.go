@@ -0,0 +1,96 @@
+package gorder
+
+import "testing"
+
+func TestTrailingComments(t *testing.T) {
+	for _, test := range []struct {
+		name, src, want string
+	}{
+		{
+			"modeline",
+			`package p
+
+func zed() {}
+
+func alpha() {}
+
+// vim: set ts=4:
+`,
+			`package p
+
+func alpha() {}
+
+func zed() {}
+
+// vim: set ts=4:
+`,
+		},
+		{
+			"code commented out and a license trailer",
+			`package p
+
+func zed() {}
+
+func alpha() {}
+
+// func old() {}
+
+/*
+End of file.
+*/
+`,
+			`package p
+
+func alpha() {}
+
+func zed() {}
+
+// func old() {}
+
+/*
+End of file.
+*/
+`,
+		},
+		{
+			"comment on the last line of the declaration staying with it",
+			`package p
+
+func zed() {}
+
+func alpha() {} // The first.
+`,
+			`package p
+
+func alpha() {} // The first.
+
+func zed() {}
+`,
+		},
+		{
+			"comment on the line of the declaration, then at the end",
+			`package p
+
+func zed() {}
+
+func alpha() {} // The first.
+
+// The end.
+`,
+			`package p
+
+func alpha() {} // The first.
+
+func zed() {}
+
+// The end.
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sortSource(t, test.src, DefaultOptions()); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package gorder
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// ReorderFS reorders the files in fsys matching pattern, see fs.Glob, and
+// returns the results keyed by file name. Nothing is written to fsys.
+func ReorderFS(fsys fs.FS, pattern string, opts Options) (map[string]Result, error) {
+	results := make(map[string]Result)
+	err := ReorderFSFunc(fsys, pattern, opts, func(name string, r Result) error {
+		results[name] = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ReorderFSFunc reorders the files in fsys matching pattern in lexical
+// order, calling fn with the result for each. It stops at the first error,
+// including one returned by fn.
+//
+// The names are only used as file names with GRPC and InsertOnly, which
+// read the package and the git history from the OS file system.
+func ReorderFSFunc(fsys fs.FS, pattern string, opts Options, fn func(name string, r Result) error) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		r, err := Reorder(name, src, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		if err := fn(name, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package gorder
+
+import (
+	"go/token"
+
+	"github.com/dave/dst"
+)
+
+// funcVarName returns the name of the var declared by d if d declares a
+// single var holding a function: a func literal, or a reference to one of
+// funcs, or a var with an explicit func type.
+//
+//	var parse = func(s string) error { ... }
+//	var handler = defaultHandler
+//	var hook func()
+func funcVarName(d *dst.GenDecl, funcs map[string]bool) (string, bool) {
+	if d.Tok != token.VAR || len(d.Specs) != 1 {
+		return "", false
+	}
+
+	vs := d.Specs[0].(*dst.ValueSpec)
+	if len(vs.Names) != 1 {
+		return "", false
+	}
+	name := vs.Names[0].Name
+
+	if _, ok := vs.Type.(*dst.FuncType); ok {
+		return name, true
+	}
+
+	if len(vs.Values) != 1 {
+		return "", false
+	}
+
+	switch v := vs.Values[0].(type) {
+	case *dst.FuncLit:
+		return name, true
+	case *dst.Ident:
+		return name, v.Path == "" && funcs[v.Name]
+	}
+
+	return "", false
+}
@@ -0,0 +1,64 @@
+package gorder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/dst/decorator"
+)
+
+// committedDeclKeys returns the keys (see declKeys) of the declarations in
+// the git HEAD version of filename. A file not yet committed has none.
+func committedDeclKeys(ctx context.Context, filename string) (map[string]bool, error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	if _, err := gitOutput(ctx, dir, "rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf("%s: -insert requires a git repository: %s", filename, err)
+	}
+
+	keys := make(map[string]bool)
+
+	rev := "HEAD:./" + base
+	if _, err := gitOutput(ctx, dir, "cat-file", "-e", rev); err != nil {
+		// Not in HEAD, so every declaration is new.
+		return keys, nil
+	}
+
+	src, err := gitOutput(ctx, dir, "show", rev)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := decorator.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s (HEAD): %s", filename, err)
+	}
+
+	for _, key := range declKeys(file.Decls) {
+		keys[key] = true
+	}
+
+	return keys, nil
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], err)
+	}
+	return out, nil
+}
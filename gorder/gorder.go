@@ -0,0 +1,1330 @@
+// Package gorder reorders the declarations in Go source files.
+package gorder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+)
+
+const (
+	magicTypeMarker = "______"
+)
+
+// Placement policies for embedded (nameless) fields.
+const (
+	EmbeddedFirst = "first"
+	EmbeddedLast  = "last"
+	EmbeddedMixed = "mixed"
+)
+
+// Placement strategies for top-level declarations.
+const (
+	// ModeDefault sorts all declarations using the weighted comparator.
+	ModeDefault = "default"
+
+	// ModeCaller sorts like ModeDefault, then moves each unexported helper
+	// function to immediately below the first function calling it.
+	ModeCaller = "caller"
+
+	// ModeAlpha sorts with AlphaSorter.
+	ModeAlpha = "alpha"
+
+	// ModeGodoc sorts with GodocSorter.
+	ModeGodoc = "godoc"
+)
+
+// Size tiebreakers for declarations that otherwise sort equal.
+const (
+	SizeNone = "none"
+	SizeAsc  = "asc"  // Short declarations first.
+	SizeDesc = "desc" // Long declarations first.
+)
+
+// Placement of the errors section.
+const (
+	ErrorsNone   = "none"
+	ErrorsTop    = "top"
+	ErrorsBottom = "bottom"
+)
+
+// Options holds the settings that control how a file is reordered.
+type Options struct {
+	// Embedded is the placement policy for embedded fields in interfaces and
+	// structs, one of EmbeddedFirst, EmbeddedLast or EmbeddedMixed.
+	Embedded string
+
+	// StructLiterals enables sorting of the fields in keyed struct literals
+	// whose values are free of side effects.
+	StructLiterals bool
+
+	// MapLiterals enables sorting of the entries in map literals where all
+	// keys are constant literals.
+	MapLiterals bool
+
+	// Mode is the placement strategy, one of ModeDefault, ModeCaller,
+	// ModeAlpha or ModeGodoc.
+	Mode string
+
+	// Sorter overrides the Sorter chosen by Mode.
+	Sorter Sorter
+
+	// Size is the size tiebreaker, one of SizeNone, SizeAsc or SizeDesc.
+	Size string
+
+	// Minimal keeps the longest already ordered subsequence of declarations
+	// in place and only relocates the declarations out of order.
+	Minimal bool
+
+	// InsertOnly keeps declarations present in the git HEAD version of the
+	// file in their relative order and only moves the new ones.
+	InsertOnly bool
+
+	// ChangedLines, if not nil, holds the lines changed in the source, e.g.
+	// from a diff. Only the declarations overlapping them move, the others
+	// keep their relative order as with InsertOnly.
+	ChangedLines []LineRange
+
+	// Kinds is the set of declaration kinds to sort.
+	Kinds Kind
+
+	// StructFields enables sorting of the fields in struct types.
+	StructFields bool
+
+	// ExportedTypesFirst places exported types, with their methods, before
+	// the unexported types.
+	ExportedTypesFirst bool
+
+	// Errors is the placement of the errors section, one of ErrorsNone,
+	// ErrorsTop or ErrorsBottom.
+	Errors string
+
+	// FlagsNearMain places package level flag.* var declarations right
+	// before func main. Only applies to package main.
+	FlagsNearMain bool
+
+	// Pairs holds groups of method names, e.g. Open and Close, that are
+	// kept together and in the given order on the same receiver. None by
+	// default, see DefaultPairs.
+	Pairs [][]string
+
+	// NormalizeSpace separates top-level declarations by exactly one blank
+	// line after sorting.
+	NormalizeSpace bool
+
+	// Banners maintains a banner comment above each section.
+	Banners bool
+
+	// Outline maintains an outline of the file's types and functions in a
+	// comment after the imports.
+	Outline bool
+
+	// GRPC orders the methods of types embedding a generated
+	// UnimplementedXxxServer as they are declared in the XxxServer interface.
+	GRPC bool
+
+	// Align holds declaration keys, as in Result.Order, to order the
+	// declarations also found in the file by. This is used to keep platform
+	// variant files, e.g. foo_linux.go and foo_windows.go, aligned.
+	Align []string
+
+	// Imports merges the import declarations and sorts the imports in
+	// groups: the standard library, other imports and those matching
+	// LocalPrefixes. Duplicate imports are removed.
+	Imports bool
+
+	// LocalPrefixes holds the import path prefixes of the local imports,
+	// grouped last with Imports, as with goimports -local.
+	LocalPrefixes []string
+
+	// Weights holds the section weights.
+	Weights Weights
+
+	// FuncVars sorts package level vars holding a func literal or a
+	// reference to a function in the file as if they were functions.
+	FuncVars bool
+
+	// Prefixes holds the name prefixes ignored when comparing names, so
+	// e.g. GetFoo and SetFoo sort next to Foo.
+	Prefixes []string
+
+	// Directives enables the //gorder: comment directives.
+	Directives bool
+
+	// Constructors holds the name prefixes of constructor functions,
+	// matched with the case of the first letter ignored.
+	Constructors []string
+
+	// CtorReturn also treats functions returning a type declared in the
+	// file as constructors.
+	CtorReturn bool
+
+	// StripLineDirectives removes the //line and /*line*/ directives before
+	// sorting. Files with line directives are otherwise left as is, with an
+	// error, if sorting would change them. The lines of the moves are those
+	// of the source without the directives.
+	StripLineDirectives bool
+
+	// Lang is the Go language version the source targets, e.g. go1.21. If
+	// set, files using newer language features are left as is with an
+	// error.
+	Lang string
+
+	// Stubs is the placement of functions without a body, implemented in
+	// assembly, and of declarations with a //go:linkname directive, one of
+	// StubsKeep, StubsGroup or StubsSort.
+	Stubs string
+
+	// FloatingComments is the placement of free-floating comments, one of
+	// FloatingNext, FloatingPrevious or FloatingKeep.
+	FloatingComments string
+
+	// SelfCheck sorts the sorted source again and returns an error if that
+	// changes it further, which would be a bug in the ordering.
+	SelfCheck bool
+
+	// MethodsAsFuncs sorts methods by name among the plain functions
+	// instead of below their receiver type.
+	MethodsAsFuncs bool
+
+	// BeforeSort, if set, is called with the parsed file before it is
+	// sorted by Reorder and the functions built on it.
+	BeforeSort func(file *dst.File) error
+
+	// AfterSort, if set, is called with the sorted file and its moves
+	// before it is printed. The moves' NewLine is not known yet and is
+	// filled in after printing.
+	AfterSort func(file *dst.File, moves []Move) error
+
+	// Format, if set, formats the sorted source, e.g. with
+	// go/format.Source, before it is returned. It is called with the
+	// printed source, with \n line endings, after it is checked.
+	Format func(src []byte) ([]byte, error)
+}
+
+// DefaultOptions returns the options the gorder command uses by default.
+func DefaultOptions() Options {
+	return Options{
+		Embedded:     EmbeddedFirst,
+		Mode:         ModeDefault,
+		Size:         SizeNone,
+		Kinds:        KindAll,
+		Errors:       ErrorsNone,
+		Weights:      DefaultWeights,
+		Prefixes:     append([]string(nil), DefaultPrefixes...),
+		Directives:   true,
+		Constructors: []string{"New"},
+
+		FloatingComments: FloatingNext,
+		Stubs:            StubsKeep,
+	}
+}
+
+// Validate reports whether the settings in o are valid.
+func (o Options) Validate() error {
+	switch o.Embedded {
+	case EmbeddedFirst, EmbeddedLast, EmbeddedMixed:
+	default:
+		return fmt.Errorf("invalid -embedded value %q", o.Embedded)
+	}
+
+	switch o.Mode {
+	case ModeDefault, ModeCaller, ModeAlpha, ModeGodoc:
+	default:
+		return fmt.Errorf("invalid -mode value %q", o.Mode)
+	}
+
+	switch o.Size {
+	case SizeNone, SizeAsc, SizeDesc:
+	default:
+		return fmt.Errorf("invalid -size value %q", o.Size)
+	}
+
+	switch o.Errors {
+	case ErrorsNone, ErrorsTop, ErrorsBottom:
+	default:
+		return fmt.Errorf("invalid -errors value %q", o.Errors)
+	}
+
+	if o.Lang != "" {
+		if err := validateLang(o.Lang); err != nil {
+			return err
+		}
+	}
+
+	switch o.Stubs {
+	case StubsKeep, StubsGroup, StubsSort:
+	default:
+		return fmt.Errorf("invalid -stubs value %q", o.Stubs)
+	}
+
+	switch o.FloatingComments {
+	case FloatingNext, FloatingPrevious, FloatingKeep:
+	default:
+		return fmt.Errorf("invalid -floating value %q", o.FloatingComments)
+	}
+
+	if o.Minimal && o.InsertOnly {
+		return errors.New("-minimal and -insert cannot be combined")
+	}
+
+	if o.Minimal && o.ChangedLines != nil {
+		return errors.New("-minimal and -hunks cannot be combined")
+	}
+
+	return nil
+}
+
+// LineRange is a range of lines, counted from 1, from Start to End
+// inclusive. A range with End one less than Start is the empty range
+// between those lines, e.g. where lines were removed; it overlaps the
+// declaration spanning both.
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Result is the result of reordering a file.
+type Result struct {
+	// Src is the reordered source.
+	Src []byte
+
+	// Order holds the keys of the top-level declarations in their new
+	// order, on the form used by Options.Align.
+	Order []string
+
+	// Moves holds the top-level declarations that changed position.
+	Moves []Move
+}
+
+// Process reorders the Go source src. Use ProcessFile or Reorder with
+// Options.GRPC and Options.InsertOnly, which need the file name.
+func Process(src []byte, opts Options) ([]byte, error) {
+	r, err := Reorder("", src, opts)
+	return r.Src, err
+}
+
+// Order reads Go source from r and writes it reordered to w. Nothing is
+// written if the source cannot be processed.
+func Order(w io.Writer, r io.Reader, opts Options) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	out, err := Process(src, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// ProcessFile reads and reorders the Go source file filename.
+func ProcessFile(filename string, opts Options) ([]byte, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Reorder(filename, src, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return r.Src, nil
+}
+
+// Reorder reorders the Go source src of the file filename, which is only
+// used to locate the file's package with GRPC and its git history with
+// InsertOnly.
+func Reorder(filename string, src []byte, opts Options) (Result, error) {
+	return ReorderContext(context.Background(), filename, src, opts)
+}
+
+// ReorderContext is like Reorder, but stops with the context's error when
+// ctx is done.
+func ReorderContext(ctx context.Context, filename string, src []byte, opts Options) (Result, error) {
+	if err := opts.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	if opts.StripLineDirectives {
+		src = StripLineDirectives(src)
+	}
+
+	if r, ok := prescan(ctx, filename, src, opts); ok {
+		return r, nil
+	}
+
+	fset := token.NewFileSet()
+	dec := decorator.NewDecorator(fset)
+	file, err := parse(dec, src)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if opts.Lang != "" {
+		if err := checkLang(fset, dec.Ast.Nodes[file].(*ast.File), opts.Lang); err != nil {
+			return Result{}, err
+		}
+	}
+
+	var lines map[dst.Decl]int
+	if opts.Size != SizeNone {
+		lines = declLines(fset, dec.Ast.Nodes, file.Decls)
+	}
+
+	var changed map[dst.Decl]bool
+	if opts.ChangedLines != nil {
+		changed = changedDecls(fset, dec.Ast.Nodes, file.Decls, opts.ChangedLines)
+	}
+
+	before := append([]dst.Decl(nil), file.Decls...)
+	oldLines := make(map[dst.Decl]int, len(before))
+	for _, d := range before {
+		if n, ok := dec.Ast.Nodes[d]; ok {
+			oldLines[d] = fset.Position(n.Pos()).Line
+		}
+	}
+
+	if opts.BeforeSort != nil {
+		if err := opts.BeforeSort(file); err != nil {
+			return Result{}, err
+		}
+	}
+
+	if err := sortFile(ctx, filename, file, lines, changed, opts); err != nil {
+		return Result{}, err
+	}
+
+	after := append([]dst.Decl(nil), file.Decls...)
+	moves := declMoves(before, after, oldLines)
+
+	if opts.AfterSort != nil {
+		if err := opts.AfterSort(file, moves); err != nil {
+			return Result{}, err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	keys := declKeys(file.Decls)
+	order := make([]string, len(file.Decls))
+	for i, d := range file.Decls {
+		order[i] = keys[d]
+	}
+
+	b := getBuffer(len(src))
+	defer putBuffer(b)
+	if err := decorator.Fprint(b, file); err != nil {
+		return Result{}, err
+	}
+	out := restoreLineEndings(src, b.Bytes())
+
+	if err := checkOutput(src, out, opts); err != nil {
+		return Result{}, err
+	}
+
+	if err := checkBuildConstraints(src, out); err != nil {
+		return Result{}, err
+	}
+
+	if err := checkCgo(src, out); err != nil {
+		return Result{}, err
+	}
+
+	if err := checkLineDirectives(src, out); err != nil {
+		return Result{}, err
+	}
+
+	if opts.Format != nil {
+		formatted, err := opts.Format(b.Bytes())
+		if err != nil {
+			return Result{}, fmt.Errorf("format: %w", err)
+		}
+		out = restoreLineEndings(src, formatted)
+	}
+
+	if err := moveLines(moves, after, file.Decls, out); err != nil {
+		return Result{}, err
+	}
+
+	if opts.SelfCheck {
+		if err := checkIdempotent(ctx, filename, out, opts); err != nil {
+			return Result{}, err
+		}
+	}
+
+	// out may share the pooled buffer.
+	if bytes.Equal(out, src) {
+		out = src
+	} else {
+		out = bytes.Clone(out)
+	}
+
+	return Result{Src: out, Order: order, Moves: moves}, nil
+}
+
+// parse parses and decorates src with dec. dst may panic on the partial
+// file go/parser returns for some syntax errors, the syntax error is
+// returned then.
+func parse(dec *decorator.Decorator, src []byte) (file *dst.File, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, err = parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution); err == nil {
+				err = fmt.Errorf("internal error, leaving the file as is: %v", r)
+			}
+		}
+	}()
+	return dec.Parse(src)
+}
+
+// bufferPool holds the buffers the files are printed to.
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// getBuffer returns an empty buffer from the pool, with room for n bytes.
+func getBuffer(n int) *bytes.Buffer {
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	b.Grow(n + n/8)
+	return b
+}
+
+// maxPooledBuffer is the capacity above which buffers are not kept, so a
+// single large file does not pin its buffer for the rest of the run.
+const maxPooledBuffer = 4 << 20
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() <= maxPooledBuffer {
+		bufferPool.Put(b)
+	}
+}
+
+// SortFile sorts file in place. The Size tiebreaker and ChangedLines count
+// the lines as printed. GRPC and InsertOnly need the file name, use Reorder for those.
+func SortFile(file *dst.File, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	var lines map[dst.Decl]int
+	if opts.Size != SizeNone {
+		r := decorator.NewRestorer()
+		if _, err := r.RestoreFile(file); err != nil {
+			return err
+		}
+		lines = declLines(r.Fset, r.Ast.Nodes, file.Decls)
+	}
+
+	var changed map[dst.Decl]bool
+	if opts.ChangedLines != nil {
+		r := decorator.NewRestorer()
+		if _, err := r.RestoreFile(file); err != nil {
+			return err
+		}
+		changed = changedDecls(r.Fset, r.Ast.Nodes, file.Decls, opts.ChangedLines)
+	}
+
+	return sortFile(context.Background(), "", file, lines, changed, opts)
+}
+
+// SortDecls sorts the top-level declarations decls in place. The file level
+// features, e.g. directives, banners and the outline, need SortFile.
+func SortDecls(decls []dst.Decl, opts Options) {
+	sorted := decls
+	var stubs map[dst.Decl][]dst.Decl
+	if opts.Stubs == StubsKeep {
+		sorted, stubs = detachStubs(decls)
+	}
+	original := append([]dst.Decl(nil), sorted...)
+	sortDecls(sorted, opts, fileInfo{})
+	if opts.Mode == ModeCaller && opts.Kinds.has(KindFunc) {
+		if err := placeHelpers(sorted, original); err != nil {
+			// Keep the decls valid, in their sorted order.
+			sortDecls(sorted, opts, fileInfo{})
+		}
+	}
+	placeStubs(sorted, opts.Stubs)
+	if stubs != nil {
+		copy(decls, attachStubs(sorted, stubs))
+	}
+}
+
+// sortFile sorts file in place. The file name is used with GRPC and
+// InsertOnly. lines holds the line count of each declaration, used with
+// the Size tiebreaker, and changed the declarations overlapping
+// ChangedLines.
+func sortFile(ctx context.Context, filename string, file *dst.File, lines map[dst.Decl]int, changed map[dst.Decl]bool, opts Options) (err error) {
+	// Report unexpected input as an error for the file, not a crash of the
+	// whole run.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error, leaving the file as is: %v", r)
+		}
+	}()
+
+	if filename == "" && (opts.GRPC || opts.InsertOnly) {
+		return errors.New("GRPC and InsertOnly need the file name")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if file.Name.Name != "main" {
+		opts.FlagsNearMain = false
+	}
+
+	detachToolDirectives(file)
+
+	if opts.Directives {
+		detachFileDirectives(file)
+
+		if args, found := fileDirective(file, directiveWeights); found {
+			if opts.Weights, err = parseWeights(args, opts.Weights); err != nil {
+				return err
+			}
+		}
+	}
+
+	info := fileInfo{lines: lines}
+
+	if opts.GRPC {
+		if info.methodOrder, err = grpcMethodOrder(filename, file); err != nil {
+			return err
+		}
+	}
+
+	var groups map[dst.Decl][]dst.Decl
+	if opts.Directives {
+		applyOrderDirectives(file, &info)
+
+		if groups, err = collectGroups(file.Decls); err != nil {
+			return err
+		}
+	}
+
+	var existing map[string]bool
+	if opts.InsertOnly {
+		if existing, err = committedDeclKeys(ctx, filename); err != nil {
+			return err
+		}
+	}
+
+	// Interface and struct types whose members are left alone.
+	keep := make(map[dst.Node]bool)
+
+	dst.Inspect(file, func(n dst.Node) bool {
+		switch v := n.(type) {
+		case *dst.GenDecl:
+			if v.Tok == token.TYPE {
+				for _, spec := range v.Specs {
+					ts := spec.(*dst.TypeSpec)
+					if opts.Directives && (hasDirective(v.Decs.Start, directiveKeep) || hasDirective(ts.Decs.Start, directiveKeep)) {
+						keep[ts.Type] = true
+					}
+				}
+			}
+		case *dst.File:
+			if opts.Imports {
+				organizeImports(v, opts.LocalPrefixes)
+			}
+			if opts.Banners {
+				removeBanners(v.Decls)
+			}
+			if opts.Outline {
+				removeOutline(v.Decls)
+			}
+			trailing := detachTrailingComments(v.Decls)
+			floating := detachFloating(v.Decls, opts.FloatingComments)
+			v.Decls = collapseGroups(v.Decls, groups)
+			var stubs map[dst.Decl][]dst.Decl
+			if opts.Stubs == StubsKeep {
+				v.Decls, stubs = detachStubs(v.Decls)
+			}
+			original := append([]dst.Decl(nil), v.Decls...)
+			sortDecls(v.Decls, opts, info)
+			if opts.Mode == ModeCaller && opts.Kinds.has(KindFunc) {
+				if err = placeHelpers(v.Decls, original); err != nil {
+					return false
+				}
+			}
+			if opts.Minimal {
+				copy(v.Decls, relocateMinimal(original, v.Decls))
+			}
+			if opts.InsertOnly || opts.ChangedLines != nil {
+				keep := make(map[dst.Decl]bool)
+				for d, key := range declKeys(original) {
+					keep[d] = (!opts.InsertOnly || existing[key]) && !changed[d]
+				}
+				copy(v.Decls, relocate(original, v.Decls, keep))
+			}
+			if opts.Align != nil {
+				alignDecls(v.Decls, opts.Align)
+			}
+			placeStubs(v.Decls, opts.Stubs)
+			if stubs != nil {
+				v.Decls = attachStubs(v.Decls, stubs)
+			}
+			if opts.Banners {
+				addBanners(v.Decls, opts.sorter().Ranker(v.Decls, opts), opts.Weights)
+			}
+			v.Decls = expandGroups(v.Decls, groups)
+			attachFloating(v.Decls, floating)
+			if opts.Outline {
+				addOutline(v.Decls)
+			}
+			attachTrailingComments(v.Decls, trailing)
+			if opts.NormalizeSpace {
+				normalizeSpacing(v.Decls)
+			}
+		case *dst.InterfaceType:
+			if opts.Kinds.has(KindInterface) && !keep[v] {
+				SortFieldList(v.Methods, opts)
+			}
+		case *dst.CompositeLit:
+			if opts.StructLiterals && isKeyedStructLit(v) {
+				sortKeyedElts(v.Elts)
+			}
+			if opts.MapLiterals && isConstKeyedMapLit(v) {
+				sortMapElts(v.Elts)
+			}
+		case *dst.StructType:
+			if opts.StructFields && opts.Kinds.has(KindStruct) && !keep[v] {
+				SortFieldList(v.Fields, opts)
+			}
+		case *dst.FieldList:
+		case nil:
+		default:
+
+		}
+
+		return true
+
+	})
+
+	return err
+}
+
+// SortFieldList sorts the methods of an interface type or the fields of a
+// struct type in place.
+func SortFieldList(fields *dst.FieldList, opts Options) {
+	sort.SliceStable(fields.List, func(i, j int) bool {
+		fi, fj := fields.List[i], fields.List[j]
+		// Mixed fields all compare by name, so the order is the same for
+		// any input order.
+		if opts.Embedded == EmbeddedMixed {
+			return lesss(fieldName(fi), fieldName(fj), opts.Prefixes, isNewField)
+		}
+
+		ni, nj := len(fi.Names), len(fj.Names)
+		if ni == 0 && nj == 0 {
+			return less(fi.Type, fj.Type, opts.Prefixes, isNewField)
+		}
+
+		if ni == 0 {
+			return opts.Embedded != EmbeddedLast
+		}
+
+		if nj == 0 {
+			return opts.Embedded == EmbeddedLast
+		}
+
+		ll := lessStringers(fi.Names[0], fj.Names[0], opts.Prefixes, isNewField)
+
+		return ll
+	})
+}
+
+// fileInfo holds information about a file gathered before sorting.
+type fileInfo struct {
+	// lines holds the line count of each top-level declaration. It is only
+	// set when a size tiebreaker is used.
+	lines map[dst.Decl]int
+
+	// methodOrder holds, per receiver, method names that go first and in
+	// the given order.
+	methodOrder map[string][]string
+
+	// declOrder holds top-level declaration names that go first and in the
+	// given order.
+	declOrder []string
+}
+
+// sortDecls sorts decls in place. Declarations of kinds not selected in opts
+// keep their position.
+func sortDecls(decls []dst.Decl, opts Options, info fileInfo) {
+	sortUnpinned(decls, func(d dst.Decl) bool {
+		k := declKind(d)
+		return k != 0 && !opts.Kinds.has(k)
+	}, func(decls []dst.Decl) {
+		sortAllDecls(decls, opts, info)
+	})
+}
+
+// Weights holds the weight of each section of declarations. Less means
+// higher up. We do some adjustments between these, so keep some empty space.
+type Weights struct {
+	ErrorsBottom int `toml:"errorsbottom"`
+	Func         int `toml:"func"`
+	Type         int `toml:"type"`
+	Constructor  int `toml:"constructor"` // newSomething
+	Exported     int `toml:"exported"`
+	Main         int `toml:"main"`
+	Flags        int `toml:"flags"`
+	ErrorsTop    int `toml:"errorstop"`
+}
+
+// DefaultWeights are the section weights used by default.
+var DefaultWeights = Weights{
+	ErrorsBottom: 300,
+	Func:         200,
+	Type:         100,
+	Constructor:  50,
+	Exported:     30,
+	Main:         10,
+	Flags:        9, // Right before main.
+	ErrorsTop:    5,
+}
+
+// declRanker computes the sort name and weight of top-level declarations.
+type declRanker struct {
+	opts       Options
+	errorTypes map[string]bool
+
+	// The names of the plain functions in the file.
+	funcs map[string]bool
+
+	// The names of the types in the file, set with -ctorreturn.
+	types map[string]bool
+
+	// The names of the plain functions returning one of types, set with
+	// -ctorreturn.
+	ctors map[string]bool
+
+	// The receiver types of the methods in the file.
+	receivers map[string]bool
+}
+
+func newDeclRanker(decls []dst.Decl, opts Options) *declRanker {
+	r := &declRanker{opts: opts, receivers: make(map[string]bool)}
+	for _, d := range decls {
+		if f, ok := d.(*dst.FuncDecl); ok && f.Recv != nil {
+			r.receivers[fieldListName(f.Recv)] = true
+		}
+	}
+	if opts.Errors != ErrorsNone {
+		r.errorTypes = errorTypeNames(decls)
+	}
+	if opts.FuncVars {
+		r.funcs = make(map[string]bool)
+		for _, d := range decls {
+			if f, ok := d.(*dst.FuncDecl); ok && f.Recv == nil {
+				r.funcs[f.Name.Name] = true
+			}
+		}
+	}
+	if opts.CtorReturn {
+		r.types = make(map[string]bool)
+		for _, d := range decls {
+			if g, ok := d.(*dst.GenDecl); ok && g.Tok == token.TYPE {
+				for _, spec := range g.Specs {
+					r.types[spec.(*dst.TypeSpec).Name.Name] = true
+				}
+			}
+		}
+		r.ctors = make(map[string]bool)
+		for _, d := range decls {
+			if f, ok := d.(*dst.FuncDecl); ok && f.Recv == nil && r.returnsFileType(f) {
+				r.ctors[f.Name.Name] = true
+			}
+		}
+	}
+	return r
+}
+
+// Key returns the sort name and weight of d. The weight is -1 for
+// declarations without an opinionated position.
+func (r *declRanker) Key(d dst.Decl) (string, int) {
+	s, weight := r.funcName(d)
+	if weight != -1 {
+		return s, weight
+	}
+
+	return r.genName(d)
+
+}
+
+// Less compares the names, ignoring the common prefixes.
+func (r *declRanker) Less(a, b string) bool {
+	return lesss(a, b, r.opts.Prefixes, r.isConstructor)
+}
+
+func (r *declRanker) errorsWeight() int {
+	if r.opts.Errors == ErrorsBottom {
+		return r.opts.Weights.ErrorsBottom
+	}
+	return r.opts.Weights.ErrorsTop
+}
+
+func (r *declRanker) funcName(d dst.Decl) (string, int) {
+	f, ok := d.(*dst.FuncDecl)
+	if !ok {
+		return "", -1
+	}
+
+	fr := fieldListName(f.Recv)
+
+	name := f.Name.String()
+
+	if fr == "" {
+		return name, r.funcNameWeight(name, r.returnsFileType(f))
+	}
+
+	if r.opts.MethodsAsFuncs {
+		return name, r.funcNameWeight(name, false)
+	}
+
+	// This is a method. We want that below the receiver type definition, if possible.
+	if r.errorTypes[fr] {
+		return fmt.Sprintf("%s.%s", fr, name), r.errorsWeight()
+	}
+	return fmt.Sprintf("%s.%s", fr, name), r.opts.Weights.Type
+
+}
+
+// funcNameWeight returns the weight of a plain function with the given name.
+// The function is a constructor if ctor is set or its name has one of the
+// constructor prefixes.
+func (r *declRanker) funcNameWeight(name string, ctor bool) int {
+	w := r.opts.Weights
+
+	if name == "main" {
+		return w.Main
+	}
+
+	ctor = ctor || isConstructorName(name, r.opts.Constructors)
+
+	if token.IsExported(name) {
+		weight := w.Exported
+		if ctor {
+			weight--
+		}
+		return weight
+	}
+
+	if ctor {
+		return w.Constructor
+	}
+
+	return w.Func
+}
+
+// isConstructor reports whether the name, or sort key, of a declaration is
+// that of a constructor: with a constructor prefix or, with CtorReturn, a
+// plain function returning a type declared in the file.
+func (r *declRanker) isConstructor(key string) bool {
+	recv, name := splitOnDot(key)
+	return isConstructorName(name, r.opts.Constructors) || recv == "" && r.ctors[name]
+}
+
+// isNewField reports whether the name of a field or interface method starts
+// with New. The configurable constructors only apply to declarations.
+func isNewField(key string) bool {
+	_, name := splitOnDot(key)
+	return strings.HasPrefix(name, "New")
+}
+
+// isConstructorName reports whether name starts with one of prefixes, e.g.
+// New matching both NewFoo and newFoo.
+func isConstructorName(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			continue
+		}
+		if strings.HasPrefix(name, prefix) || strings.HasPrefix(name, lowerFirst(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// returnsFileType reports whether f returns a type declared in the file, or
+// a pointer to one, as its first result. Only used with CtorReturn.
+func (r *declRanker) returnsFileType(f *dst.FuncDecl) bool {
+	return r.returnedFileType(f) != ""
+}
+
+// returnedFileType returns the type declared in the file that f returns, or
+// a pointer to which it returns, as its first result.
+func (r *declRanker) returnedFileType(f *dst.FuncDecl) string {
+	if r.types == nil || f.Type.Results == nil || len(f.Type.Results.List) == 0 {
+		return ""
+	}
+	if name := baseTypeName(f.Type.Results.List[0].Type); r.types[name] {
+		return name
+	}
+	return ""
+}
+
+// typeDeclName returns the name of the type declaration m sorts by: the
+// first type with methods in the file, so a grouped type (...) block sorts
+// with the methods of one of its types, or else the first type. It returns ""
+// for an empty block.
+func (r *declRanker) typeDeclName(m *dst.GenDecl) string {
+	if len(m.Specs) == 0 {
+		return ""
+	}
+	for _, spec := range m.Specs {
+		if name := spec.(*dst.TypeSpec).Name.Name; r.receivers[name] {
+			return name
+		}
+	}
+	return m.Specs[0].(*dst.TypeSpec).Name.Name
+}
+
+func (r *declRanker) genName(d dst.Decl) (string, int) {
+	m, ok := d.(*dst.GenDecl)
+	if !ok {
+		return "", -1
+	}
+
+	if m.Tok == token.TYPE {
+		name := r.typeDeclName(m)
+		if name == "" {
+			return "", -1
+		}
+		// Return on the form receiver.____ to make sure it's grouped with the
+		// methods it owns.
+		if r.errorTypes[name] {
+			return name + "." + magicTypeMarker, r.errorsWeight()
+		}
+		return name + "." + magicTypeMarker, r.opts.Weights.Type
+	}
+
+	if r.opts.Errors != ErrorsNone && isSentinelErrorDecl(m) {
+		return m.Specs[0].(*dst.ValueSpec).Names[0].String(), r.errorsWeight()
+	}
+
+	if r.opts.FlagsNearMain && isFlagDecl(m) {
+		return m.Specs[0].(*dst.ValueSpec).Names[0].String(), r.opts.Weights.Flags
+	}
+
+	if r.opts.FuncVars {
+		if name, ok := funcVarName(m, r.funcs); ok {
+			return name, r.funcNameWeight(name, false)
+		}
+	}
+
+	return "", -1
+
+}
+
+func sortAllDecls(decls []dst.Decl, opts Options, info fileInfo) {
+	ranker := opts.sorter().Ranker(decls, opts)
+	pairNames := newPairNamer(opts.Pairs, decls)
+	methodIndex := newMethodIndex(info.methodOrder)
+	declIndex := newDeclIndex(info.declOrder)
+
+	sort.SliceStable(decls, func(i, j int) bool {
+		di, dj := decls[i], decls[j]
+
+		if preserveOrder(di) || preserveOrder(dj) {
+			return i < j
+		}
+
+		if ii, ij := declIndex.index(di), declIndex.index(dj); ii != ij {
+			if ii == -1 || ij == -1 {
+				return ij == -1
+			}
+			return ii < ij
+		}
+
+		si, weighti := ranker.Key(di)
+		sj, weightj := ranker.Key(dj)
+
+		if weighti == -1 && weightj == -1 {
+			return i < j
+		}
+
+		if weighti != weightj {
+			return weighti < weightj
+		}
+
+		ri, _ := splitOnDot(si)
+		rj, _ := splitOnDot(sj)
+
+		if opts.ExportedTypesFirst && ri != "" && rj != "" {
+			if ei, ej := token.IsExported(ri), token.IsExported(rj); ei != ej {
+				return ei
+			}
+		}
+
+		if ri != "" && ri == rj {
+			if less, ok := methodIndex.less(si, sj); ok {
+				return less
+			}
+
+			var pi, pj int
+			si, pi = pairNames.name(si)
+			sj, pj = pairNames.name(sj)
+			if si == sj {
+				return pi < pj
+			}
+		}
+
+		if opts.Size != SizeNone && !ranker.Less(si, sj) && !ranker.Less(sj, si) {
+			li, lj := info.lines[di], info.lines[dj]
+			if opts.Size == SizeDesc {
+				return li > lj
+			}
+			return li < lj
+		}
+
+		return ranker.Less(si, sj)
+	})
+}
+
+// declLines returns the number of source lines spanned by each of decls,
+// not counting doc comments, using nodes to find their positions in fset.
+func declLines(fset *token.FileSet, nodes map[dst.Node]ast.Node, decls []dst.Decl) map[dst.Decl]int {
+	lines := make(map[dst.Decl]int)
+	for _, d := range decls {
+		n, ok := nodes[d]
+		if !ok {
+			continue
+		}
+		lines[d] = fset.Position(n.End()).Line - fset.Position(n.Pos()).Line + 1
+	}
+	return lines
+}
+
+// changedDecls returns the declarations in decls, with their doc comments,
+// overlapping ranges.
+func changedDecls(fset *token.FileSet, nodes map[dst.Node]ast.Node, decls []dst.Decl, ranges []LineRange) map[dst.Decl]bool {
+	changed := make(map[dst.Decl]bool)
+	for _, d := range decls {
+		n, ok := nodes[d]
+		if !ok {
+			continue
+		}
+		pos := n.Pos()
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Doc != nil {
+				pos = n.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if n.Doc != nil {
+				pos = n.Doc.Pos()
+			}
+		}
+		start, end := fset.Position(pos).Line, fset.Position(n.End()).Line
+		for _, r := range ranges {
+			if start <= r.End && r.Start <= end {
+				changed[d] = true
+				break
+			}
+		}
+	}
+	return changed
+}
+
+func fieldListName(list *dst.FieldList) string {
+	if list == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, v := range list.List {
+		b.WriteString(baseTypeName(v.Type))
+	}
+
+	return b.String()
+}
+
+// baseTypeName returns the name of the type in the receiver or result type
+// expression expr, e.g. Foo for *Foo[K, V], or "" if it is not a named type.
+func baseTypeName(expr dst.Expr) string {
+	switch x := expr.(type) {
+	case *dst.Ident:
+		return x.Name
+	case *dst.StarExpr:
+		return baseTypeName(x.X)
+	case *dst.ParenExpr:
+		return baseTypeName(x.X)
+	case *dst.IndexExpr:
+		return baseTypeName(x.X)
+	case *dst.IndexListExpr:
+		return baseTypeName(x.X)
+	}
+	return ""
+}
+
+// fieldName returns the name of a field as Go sees it: its first name or, for
+// embedded fields, the unqualified name of its type.
+func fieldName(f *dst.Field) string {
+	if len(f.Names) > 0 {
+		return f.Names[0].String()
+	}
+
+	t := f.Type
+	if s, ok := t.(*dst.StarExpr); ok {
+		t = s.X
+	}
+	switch s := t.(type) {
+	case *dst.IndexExpr:
+		t = s.X
+	case *dst.IndexListExpr:
+		t = s.X
+	}
+	if s, ok := t.(*dst.SelectorExpr); ok {
+		return s.Sel.String()
+	}
+
+	return typeName(t)
+}
+
+// typeName returns the name of the type expression x, such as an embedded
+// interface, or else its source; see exprString.
+func typeName(x dst.Expr) string {
+	switch v := x.(type) {
+	case *dst.SelectorExpr:
+		return fmt.Sprintf("%s.%s", v.X, v.Sel)
+	case *dst.Ident:
+		return v.String()
+	case *dst.StarExpr:
+		return typeName(v.X)
+	default:
+		return exprString(x)
+	}
+}
+
+func less(s, t dst.Expr, prefixes []string, ctor func(name string) bool) bool {
+	return lesss(typeName(s), typeName(t), prefixes, ctor)
+
+}
+
+func lessStringers(s1, s2 fmt.Stringer, prefixes []string, ctor func(name string) bool) bool {
+	return lesss(s1.String(), s2.String(), prefixes, ctor)
+}
+
+// weightAdjustment returns the adjustment of the weight of name when
+// comparing names, ctor telling whether it is a constructor.
+func weightAdjustment(name string, ctor bool) int {
+	w := 0
+
+	if name == magicTypeMarker {
+		w -= 5
+	}
+	// Exported funcs
+	if token.IsExported(name) {
+		w -= 2
+	}
+
+	// Constructor funcs.
+	if ctor {
+		w--
+	}
+
+	return w
+}
+
+// lesss compares the names s1 and s2, ignoring the common prefixes. ctor
+// reports whether a name is a constructor's.
+func lesss(s1, s2 string, prefixes []string, ctor func(name string) bool) bool {
+	s1r, s1name := splitOnDot(s1)
+	s2r, s2name := splitOnDot(s2)
+
+	if s1r != s2r {
+		// Different receiver types
+		return s1r < s2r
+	}
+
+	s1w := 100
+	s2w := 100
+
+	s1w += weightAdjustment(s1name, ctor(s1))
+	s2w += weightAdjustment(s2name, ctor(s2))
+
+	if s1w != s2w {
+		return s1w < s2w
+	}
+
+	var s1prefix, s2prefix string
+
+	s1name, s1prefix = trimCommonPrefix(s1name, prefixes)
+	s2name, s2prefix = trimCommonPrefix(s2name, prefixes)
+
+	if s1prefix != "" && s2prefix != "" {
+		return s1prefix < s2prefix
+	}
+
+	return s1name < s2name
+
+}
+
+// DefaultPrefixes are the name prefixes ignored when comparing names by
+// default.
+var DefaultPrefixes = []string{"Is", "Has", "Get", "All", "Create", "New", "Err", "Error", "Init", "Find", "Set", "Render"}
+
+func trimCommonPrefix(s string, prefixes []string) (string, string) {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return prefix, strings.TrimPrefix(s, prefix)
+		}
+		if strings.HasPrefix(s, strings.ToLower(prefix)) {
+			return prefix, strings.TrimPrefix(s, strings.ToLower(prefix))
+		}
+	}
+
+	return "", s
+
+}
+
+func preserveOrder(decl dst.Decl) bool {
+	switch v := decl.(type) {
+	case *dst.GenDecl:
+		return v.Tok == token.PACKAGE || v.Tok == token.IMPORT
+	default:
+		return false
+	}
+}
+
+func isFuncDecl(decl dst.Decl) bool {
+	switch decl.(type) {
+	case *dst.FuncDecl:
+		return true
+	default:
+		return false
+	}
+}
+
+// splitOnDot splits name on the form Receiver.Name on its first dot. Names
+// without a dot have no receiver.
+func splitOnDot(name string) (string, string) {
+	recv, rest, found := strings.Cut(name, ".")
+	if !found {
+		return "", name
+	}
+	return recv, rest
+}
+
+// lowerFirst returns s with its first letter in lower case.
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
@@ -0,0 +1,263 @@
+package gorder
+
+import (
+	"go/token"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+)
+
+func TestGroupedDecls(t *testing.T) {
+	for _, test := range []struct {
+		name, src, want string
+	}{
+		{
+			"type block sorting with the methods of its type with methods",
+			`package p
+
+func (b B) Zed() {}
+
+type (
+	a int
+	B struct{}
+)
+
+type C int
+
+func (c C) M() {}
+
+func (b B) Alpha() {}
+`,
+			`package p
+
+type (
+	a int
+	B struct{}
+)
+
+func (b B) Alpha() {}
+
+func (b B) Zed() {}
+
+type C int
+
+func (c C) M() {}
+`,
+		},
+		{
+			"empty blocks",
+			`package p
+
+func zed() {}
+
+type ()
+
+var ()
+
+const ()
+`,
+			`package p
+
+type ()
+
+var ()
+
+const ()
+
+func zed() {}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sortSource(t, test.src, DefaultOptions()); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTypeDeclName(t *testing.T) {
+	file, err := decorator.Parse(`package p
+
+type ()
+
+type A int
+
+type (
+	b int
+	C struct{}
+	D struct{}
+)
+
+type (
+	e int
+	f int
+)
+
+func (C) M() {}
+
+func (D) M() {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newDeclRanker(file.Decls, DefaultOptions())
+	var got []string
+	for _, d := range file.Decls {
+		if g, ok := d.(*dst.GenDecl); ok {
+			got = append(got, r.typeDeclName(g))
+		}
+	}
+	want := []string{"", "A", "C", "e"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExportedNonASCII(t *testing.T) {
+	r := newDeclRanker(nil, DefaultOptions())
+	w := DefaultWeights
+	for _, test := range []struct {
+		name string
+		want int
+	}{
+		{"Ärger", w.Exported},
+		{"ĄParse", w.Exported},
+		{"ärgerHelper", w.Func},
+		{"αHelper", w.Func},
+		// Title case letters are not upper case, so not exported.
+		{"ǅTitle", w.Func},
+		{"NeuÄrger", w.Exported},
+		{"NewÄrger", w.Exported - 1},
+	} {
+		if got := r.funcNameWeight(test.name, false); got != test.want {
+			t.Errorf("%s: got weight %d, want %d", test.name, got, test.want)
+		}
+		if exported := test.want != w.Func; exported != token.IsExported(test.name) {
+			t.Errorf("%s: exported %t, token.IsExported %t", test.name, exported, token.IsExported(test.name))
+		}
+	}
+
+	for _, test := range []struct {
+		prefix, name string
+		want         bool
+	}{
+		{"Ärger", "ärgerHelper", true},
+		{"Ärger", "ÄrgerHelper", true},
+		{"New", "newÄrger", true},
+		{"New", "neuÄrger", false},
+	} {
+		if got := isConstructorName(test.name, []string{test.prefix}); got != test.want {
+			t.Errorf("isConstructorName(%q, %q): got %t, want %t", test.name, test.prefix, got, test.want)
+		}
+	}
+}
+
+func TestSortNonASCII(t *testing.T) {
+	src := `package p
+
+func ärgerHelper() {}
+
+type Ärger struct{}
+
+func (Ärger) ĄParse() {}
+
+func NeuÄrger() Ärger { return Ärger{} }
+
+func ĄParse() {}
+
+func αHelper() {}
+
+func ǅTitle() {}
+`
+	want := `package p
+
+func NeuÄrger() Ärger { return Ärger{} }
+
+func ĄParse() {}
+
+type Ärger struct{}
+
+func (Ärger) ĄParse() {}
+
+func ärgerHelper() {}
+
+func ǅTitle() {}
+
+func αHelper() {}
+`
+	if got := sortSource(t, src, DefaultOptions()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSortFieldListEmbeddedMixed(t *testing.T) {
+	fields := []string{"Sam()", "Zed", "io.Reader", "Beta()", "*bytes.Buffer", "aa()"}
+	want := []string{"Beta", "Buffer", "Reader", "Sam", "Zed", "aa"}
+	opts := DefaultOptions()
+	opts.Embedded = EmbeddedMixed
+
+	// Sort every permutation of the fields, the result must not depend on
+	// the order they start in.
+	var permute func(k int)
+	permute = func(k int) {
+		if k == len(fields) {
+			file, err := decorator.Parse("package p\n\ntype I interface {\n" + strings.Join(fields, "\n") + "\n}\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			list := file.Decls[0].(*dst.GenDecl).Specs[0].(*dst.TypeSpec).Type.(*dst.InterfaceType).Methods
+			SortFieldList(list, opts)
+			var got []string
+			for _, f := range list.List {
+				got = append(got, fieldName(f))
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("%q: got %q, want %q", fields, got, want)
+			}
+			return
+		}
+		for i := k; i < len(fields); i++ {
+			fields[k], fields[i] = fields[i], fields[k]
+			permute(k + 1)
+			fields[k], fields[i] = fields[i], fields[k]
+		}
+	}
+	permute(0)
+}
+
+func TestSortFieldListConstructors(t *testing.T) {
+	src := `package p
+
+type I interface {
+	alpha()
+	newFoo()
+	Next()
+	MakeBar()
+	Close()
+	NewReader()
+}
+`
+	// Only the exported New prefix moves fields up, whatever the
+	// constructors of the declarations are.
+	want := `package p
+
+type I interface {
+	NewReader()
+	Close()
+	MakeBar()
+	Next()
+	alpha()
+	newFoo()
+}
+`
+	opts := DefaultOptions()
+	opts.Constructors = []string{"New", "Make"}
+	opts.Prefixes = nil
+	if got := sortSource(t, src, opts); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
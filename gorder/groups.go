@@ -0,0 +1,119 @@
+package gorder
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// collectGroups finds the declarations enclosed in
+//
+//	//gorder:group begin
+//	...
+//	//gorder:group end
+//
+// markers and returns the members of each group keyed by its first member.
+// The end marker usually ends up in the comments of the declaration
+// following the group; it is moved to the end of the group's last member so
+// it stays with the group.
+func collectGroups(decls []dst.Decl) (map[dst.Decl][]dst.Decl, error) {
+	groups := make(map[dst.Decl][]dst.Decl)
+
+	begin := -1
+	for i, d := range decls {
+		decs := d.Decorations()
+
+		if args, found := findDirective(decs.Start, directiveGroup); found && args == "end" {
+			if begin == -1 {
+				return nil, errors.New("//gorder:group end without begin")
+			}
+			moveGroupEnd(decls[i-1], d)
+			groups[decls[begin]] = decls[begin:i]
+			begin = -1
+		}
+
+		if args, found := findDirective(decs.Start, directiveGroup); found && args == "begin" {
+			if begin != -1 {
+				return nil, errors.New("nested //gorder:group begin")
+			}
+			begin = i
+		}
+
+		if args, found := findDirective(decs.End, directiveGroup); found && args == "end" {
+			if begin == -1 {
+				return nil, errors.New("//gorder:group end without begin")
+			}
+			groups[decls[begin]] = decls[begin : i+1]
+			begin = -1
+		}
+	}
+
+	if begin != -1 {
+		return nil, errors.New("//gorder:group begin without end")
+	}
+
+	return groups, nil
+}
+
+// moveGroupEnd moves the comments in next up to and including the group end
+// marker to the end of last.
+func moveGroupEnd(last, next dst.Decl) {
+	start := next.Decorations().Start
+	for i, dec := range start {
+		if strings.HasPrefix(dec, DirectivePrefix+directiveGroup) {
+			rest := start[i+1:]
+			if len(rest) > 0 && rest[0] == "\n" {
+				rest = rest[1:]
+			}
+			end := &last.Decorations().End
+			end.Append("\n", "\n")
+			end.Append(start[:i+1]...)
+			next.Decorations().Start.Replace(rest...)
+			return
+		}
+	}
+}
+
+// collapseGroups replaces the members of each group in decls with the
+// group's first member.
+func collapseGroups(decls []dst.Decl, groups map[dst.Decl][]dst.Decl) []dst.Decl {
+	if len(groups) == 0 {
+		return decls
+	}
+
+	var (
+		units []dst.Decl
+		skip  int
+	)
+	for _, d := range decls {
+		if skip > 0 {
+			skip--
+			continue
+		}
+		units = append(units, d)
+		if members, ok := groups[d]; ok {
+			skip = len(members) - 1
+		}
+	}
+
+	return units
+}
+
+// expandGroups reverses collapseGroups.
+func expandGroups(units []dst.Decl, groups map[dst.Decl][]dst.Decl) []dst.Decl {
+	if len(groups) == 0 {
+		return units
+	}
+
+	var decls []dst.Decl
+	for _, d := range units {
+		if members, ok := groups[d]; ok {
+			decls = append(decls, members...)
+			continue
+		}
+		decls = append(decls, d)
+	}
+
+	return decls
+}
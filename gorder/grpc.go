@@ -0,0 +1,173 @@
+package gorder
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// grpcMethodOrder finds the types in file embedding a generated gRPC
+// UnimplementedXxxServer (or UnsafeXxxServer) and returns, per type, the
+// methods of the XxxServer interface in declaration order, which is the
+// order of the rpcs in the .proto file.
+func grpcMethodOrder(filename string, file *dst.File) (map[string][]string, error) {
+	dir := filepath.Dir(filename)
+	order := make(map[string][]string)
+
+	// Resolve imports relative to the file's module, not ours.
+	ctxt := build.Default
+	ctxt.Dir = dir
+
+	// Parsed interfaces per package directory.
+	cache := make(map[string]map[string][]string)
+
+	for _, d := range file.Decls {
+		g, ok := d.(*dst.GenDecl)
+		if !ok || g.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range g.Specs {
+			ts := spec.(*dst.TypeSpec)
+			st, ok := ts.Type.(*dst.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				if len(field.Names) > 0 {
+					continue
+				}
+				pkg, service := grpcService(field.Type)
+				if service == "" {
+					continue
+				}
+
+				pkgDir := dir
+				if pkg != "" {
+					importPath := importPathFor(file, pkg)
+					if importPath == "" {
+						continue
+					}
+					bp, err := ctxt.Import(importPath, dir, build.FindOnly)
+					if err != nil {
+						// Not resolvable from here; leave the methods alone.
+						continue
+					}
+					pkgDir = bp.Dir
+				}
+
+				ifaces, ok := cache[pkgDir]
+				if !ok {
+					var err error
+					if ifaces, err = interfaceMethods(pkgDir); err != nil {
+						return nil, err
+					}
+					cache[pkgDir] = ifaces
+				}
+
+				if methods, ok := ifaces[service+"Server"]; ok {
+					order[ts.Name.Name] = methods
+				}
+			}
+		}
+	}
+
+	return order, nil
+}
+
+// grpcService returns the package qualifier and service name of an embedded
+// field on the form [pkg.]UnimplementedXxxServer or [pkg.]UnsafeXxxServer.
+func grpcService(expr dst.Expr) (pkg, service string) {
+	var name string
+	switch v := expr.(type) {
+	case *dst.Ident:
+		name = v.Name
+	case *dst.SelectorExpr:
+		x, ok := v.X.(*dst.Ident)
+		if !ok {
+			return "", ""
+		}
+		pkg, name = x.Name, v.Sel.Name
+	default:
+		return "", ""
+	}
+
+	if !strings.HasSuffix(name, "Server") {
+		return "", ""
+	}
+	for _, prefix := range []string{"Unimplemented", "Unsafe"} {
+		if strings.HasPrefix(name, prefix) {
+			return pkg, strings.TrimSuffix(strings.TrimPrefix(name, prefix), "Server")
+		}
+	}
+
+	return "", ""
+}
+
+// importPathFor returns the path of the import in file referred to as name.
+func importPathFor(file *dst.File, name string) string {
+	for _, imp := range file.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return p
+			}
+			continue
+		}
+		if base := path.Base(p); base == name || strings.TrimSuffix(base, "pb") == name {
+			return p
+		}
+	}
+	return ""
+}
+
+// interfaceMethods parses the non-test Go files in dir and returns the
+// method names of each interface type in declaration order.
+func interfaceMethods(dir string) (map[string][]string, error) {
+	ifaces := make(map[string][]string)
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, d := range f.Decls {
+				g, ok := d.(*ast.GenDecl)
+				if !ok || g.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range g.Specs {
+					ts := spec.(*ast.TypeSpec)
+					it, ok := ts.Type.(*ast.InterfaceType)
+					if !ok {
+						continue
+					}
+					var methods []string
+					for _, m := range it.Methods.List {
+						for _, n := range m.Names {
+							methods = append(methods, n.Name)
+						}
+					}
+					ifaces[ts.Name.Name] = methods
+				}
+			}
+		}
+	}
+
+	return ifaces, nil
+}
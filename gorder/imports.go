@@ -0,0 +1,141 @@
+package gorder
+
+import (
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// The import groups, in order.
+const (
+	importStd = iota
+	importExternal
+	importLocal
+)
+
+// organizeImports merges the import declarations of file into the first,
+// removes duplicate imports and sorts them in groups: the standard library,
+// other imports, and those matching a prefix in local, as goimports -local
+// does. Declarations with comments of their own and import "C", with its
+// preamble, are left separate; the imports in the former are still sorted.
+func organizeImports(file *dst.File, local []string) {
+	var (
+		first *dst.GenDecl
+		decls []dst.Decl
+	)
+	for _, d := range file.Decls {
+		g, ok := d.(*dst.GenDecl)
+		switch {
+		case !ok || g.Tok != token.IMPORT || importsC(g):
+		case first == nil:
+			first = g
+		case !hasDecorations(g.Decs.NodeDecs) && len(g.Decs.Tok) == 0 && len(g.Decs.Lparen) == 0:
+			first.Specs = append(first.Specs, g.Specs...)
+			continue
+		default:
+			organizeSpecs(g, local)
+		}
+		decls = append(decls, d)
+	}
+	file.Decls = decls
+
+	if first != nil {
+		organizeSpecs(first, local)
+	}
+
+	file.Imports = file.Imports[:0]
+	for _, d := range file.Decls {
+		if g, ok := d.(*dst.GenDecl); ok && g.Tok == token.IMPORT {
+			for _, spec := range g.Specs {
+				file.Imports = append(file.Imports, spec.(*dst.ImportSpec))
+			}
+		}
+	}
+}
+
+// organizeSpecs removes the duplicate imports in g and sorts the rest in
+// groups separated by a blank line.
+func organizeSpecs(g *dst.GenDecl, local []string) {
+	seen := make(map[string]bool)
+	specs := g.Specs[:0]
+	for _, spec := range g.Specs {
+		s := spec.(*dst.ImportSpec)
+		key := importName(s) + " " + s.Path.Value
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		specs = append(specs, s)
+	}
+	g.Specs = specs
+
+	sort.SliceStable(specs, func(i, j int) bool {
+		si, sj := specs[i].(*dst.ImportSpec), specs[j].(*dst.ImportSpec)
+		pi, pj := importPath(si), importPath(sj)
+		if gi, gj := importGroup(pi, local), importGroup(pj, local); gi != gj {
+			return gi < gj
+		}
+		if pi != pj {
+			return pi < pj
+		}
+		return importName(si) < importName(sj)
+	})
+
+	for i, spec := range specs {
+		s := spec.(*dst.ImportSpec)
+		s.Decs.Before, s.Decs.After = dst.NewLine, dst.None
+		if i > 0 && importGroup(importPath(s), local) != importGroup(importPath(specs[i-1].(*dst.ImportSpec)), local) {
+			s.Decs.Before = dst.EmptyLine
+		}
+	}
+	if len(specs) > 1 {
+		g.Lparen, g.Rparen = true, true
+	}
+}
+
+// importGroup returns the group of the import path.
+func importGroup(path string, local []string) int {
+	for _, p := range local {
+		if strings.HasPrefix(path, p) || strings.TrimSuffix(p, "/") == path {
+			return importLocal
+		}
+	}
+	// As goimports, the standard library has no dot in the first element.
+	first, _, _ := strings.Cut(path, "/")
+	if !strings.Contains(first, ".") {
+		return importStd
+	}
+	return importExternal
+}
+
+func importPath(s *dst.ImportSpec) string {
+	path, err := strconv.Unquote(s.Path.Value)
+	if err != nil {
+		return s.Path.Value
+	}
+	return path
+}
+
+func importName(s *dst.ImportSpec) string {
+	if s.Name == nil {
+		return ""
+	}
+	return s.Name.Name
+}
+
+// importsC reports whether g imports "C".
+func importsC(g *dst.GenDecl) bool {
+	for _, spec := range g.Specs {
+		if s, ok := spec.(*dst.ImportSpec); ok && s.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDecorations(d dst.NodeDecs) bool {
+	return len(d.Start) > 0 || len(d.End) > 0
+}
@@ -0,0 +1,98 @@
+package gorder
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// Kind is a set of declaration kinds.
+type Kind uint
+
+const (
+	KindFunc Kind = 1 << iota
+	KindType
+	KindConst
+	KindVar
+	KindInterface // Methods in interface types.
+	KindStruct    // Fields in struct types.
+
+	KindAll = KindFunc | KindType | KindConst | KindVar | KindInterface | KindStruct
+)
+
+var kindNames = map[string]Kind{
+	"func":      KindFunc,
+	"type":      KindType,
+	"const":     KindConst,
+	"var":       KindVar,
+	"interface": KindInterface,
+	"struct":    KindStruct,
+}
+
+func (m Kind) has(k Kind) bool {
+	return m&k != 0
+}
+
+// ParseKinds parses a comma separated list of kind names. The empty string
+// means all kinds.
+func ParseKinds(s string) (Kind, error) {
+	if strings.TrimSpace(s) == "" {
+		return KindAll, nil
+	}
+
+	var m Kind
+	for _, name := range strings.Split(s, ",") {
+		k, ok := kindNames[strings.TrimSpace(name)]
+		if !ok {
+			return 0, fmt.Errorf("invalid declaration kind %q in -only", name)
+		}
+		m |= k
+	}
+
+	return m, nil
+}
+
+// declKind returns the kind of the top-level declaration d, or 0 if it is
+// not of a kind that can be selected (e.g. imports).
+func declKind(d dst.Decl) Kind {
+	switch v := d.(type) {
+	case *dst.FuncDecl:
+		return KindFunc
+	case *dst.GenDecl:
+		switch v.Tok {
+		case token.TYPE:
+			return KindType
+		case token.CONST:
+			return KindConst
+		case token.VAR:
+			return KindVar
+		}
+	}
+	return 0
+}
+
+// sortUnpinned sorts the declarations for which pinned returns false using
+// sortFn, leaving the pinned ones at their original index.
+func sortUnpinned(decls []dst.Decl, pinned func(d dst.Decl) bool, sortFn func(decls []dst.Decl)) {
+	var movable []dst.Decl
+	for _, d := range decls {
+		if !pinned(d) {
+			movable = append(movable, d)
+		}
+	}
+
+	if len(movable) == len(decls) {
+		sortFn(decls)
+		return
+	}
+
+	sortFn(movable)
+
+	for i, d := range decls {
+		if !pinned(d) {
+			decls[i], movable = movable[0], movable[1:]
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package gorder
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"go/version"
+	"runtime"
+	"strings"
+)
+
+// validateLang returns an error if lang is not a Go language version, e.g.
+// go1.21, or is newer than the Go version gorder was built with, whose
+// parser may not know its syntax.
+func validateLang(lang string) error {
+	if !version.IsValid(lang) || version.Lang(lang) != lang {
+		return fmt.Errorf("invalid -lang value %q, want a Go language version such as go1.21", lang)
+	}
+	if built := version.Lang(runtime.Version()); built != "" && version.Compare(lang, built) > 0 {
+		return fmt.Errorf("-lang %s is newer than %s, which gorder was built with", lang, built)
+	}
+	return nil
+}
+
+// checkLang returns an error if f, parsed in fset, uses language features
+// newer than the Go version lang. The file is type checked on its own, so
+// only the errors about the language version count.
+func checkLang(fset *token.FileSet, f *ast.File, lang string) error {
+	var (
+		first error
+		n     int
+	)
+	conf := types.Config{
+		GoVersion: lang,
+		Importer:  importerFunc(func(string) (*types.Package, error) { return nil, errors.New("not imported") }),
+		Error: func(err error) {
+			if e, ok := err.(types.Error); ok && strings.Contains(e.Msg, "requires go1") {
+				if n == 0 {
+					first = fmt.Errorf("%s: %s (-lang %s)", fset.Position(e.Pos), e.Msg, lang)
+				}
+				n++
+			}
+		},
+	}
+	conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+	if n > 1 {
+		return fmt.Errorf("%w (and %d more errors)", first, n-1)
+	}
+	return first
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
@@ -0,0 +1,82 @@
+package gorder
+
+import (
+	"bytes"
+	"errors"
+	"go/scanner"
+	"go/token"
+)
+
+// errLineDirectives guards against sorting a file with //line directives,
+// whose position mapping would no longer match the code below them.
+var errLineDirectives = errors.New("the file has //line directives that would no longer match, leaving the file as is")
+
+// lineDirectives returns the start and end offsets of the //line and
+// /*line*/ directives in src. The end of a //line directive includes its
+// newline.
+func lineDirectives(src []byte) [][2]int {
+	if !bytes.Contains(src, []byte("line ")) {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	var directives [][2]int
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.COMMENT {
+			continue
+		}
+		start := file.Offset(pos)
+		switch {
+		case bytes.HasPrefix(src[start:], []byte("//line ")) && (start == 0 || src[start-1] == '\n'):
+			end := len(src)
+			if i := bytes.IndexByte(src[start:], '\n'); i >= 0 {
+				end = start + i + 1
+			}
+			directives = append(directives, [2]int{start, end})
+		case bytes.HasPrefix(src[start:], []byte("/*line ")):
+			directives = append(directives, [2]int{start, start + len(lit)})
+		}
+	}
+	return directives
+}
+
+// HasLineDirectives reports whether src has //line or /*line*/ directives,
+// usually left by code generators such as goyacc, that sorting would
+// invalidate.
+func HasLineDirectives(src []byte) bool {
+	return len(lineDirectives(src)) > 0
+}
+
+// StripLineDirectives returns src without its //line and /*line*/
+// directives.
+func StripLineDirectives(src []byte) []byte {
+	directives := lineDirectives(src)
+	if len(directives) == 0 {
+		return src
+	}
+	var b bytes.Buffer
+	prev := 0
+	for _, d := range directives {
+		b.Write(src[prev:d[0]])
+		prev = d[1]
+	}
+	b.Write(src[prev:])
+	return b.Bytes()
+}
+
+// checkLineDirectives returns errLineDirectives if src has line directives
+// and out differs from it.
+func checkLineDirectives(src, out []byte) error {
+	if !bytes.Equal(src, out) && HasLineDirectives(src) {
+		return errLineDirectives
+	}
+	return nil
+}
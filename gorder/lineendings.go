@@ -0,0 +1,22 @@
+package gorder
+
+import "bytes"
+
+// usesCRLF reports whether most lines in src end with \r\n.
+func usesCRLF(src []byte) bool {
+	crlf := bytes.Count(src, []byte("\r\n"))
+	return crlf > 0 && 2*crlf > bytes.Count(src, []byte("\n"))
+}
+
+// restoreLineEndings gives out, the printed source, the line endings of src:
+// \r\n if most of its lines end with it, and no newline at the end of the
+// file if src has none. The printer always writes \n, with one at the end.
+func restoreLineEndings(src, out []byte) []byte {
+	if usesCRLF(src) {
+		out = bytes.ReplaceAll(out, []byte("\n"), []byte("\r\n"))
+	}
+	if len(src) > 0 && !bytes.HasSuffix(src, []byte("\n")) {
+		out = bytes.TrimRight(out, "\r\n")
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package gorder
+
+import (
+	"go/constant"
+	"go/token"
+	"sort"
+
+	"github.com/dave/dst"
+)
+
+// isKeyedStructLit reports whether lit looks like a keyed struct literal,
+// i.e. all of its elements are on the form Field: value. Literals with an
+// elided type are assumed to be structs if all keys are plain identifiers.
+// The values must be free of side effects, as they are evaluated in the new
+// order.
+func isKeyedStructLit(lit *dst.CompositeLit) bool {
+	switch lit.Type.(type) {
+	case *dst.MapType, *dst.ArrayType:
+		return false
+	}
+
+	if len(lit.Elts) < 2 {
+		return false
+	}
+
+	for _, e := range lit.Elts {
+		kv, ok := e.(*dst.KeyValueExpr)
+		if !ok {
+			return false
+		}
+		if _, ok := kv.Key.(*dst.Ident); !ok {
+			return false
+		}
+		if !isPure(kv.Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPure reports whether evaluating e has no side effects: e is made of
+// literals, identifiers and selectors.
+func isPure(e dst.Expr) bool {
+	switch v := e.(type) {
+	case *dst.BasicLit, *dst.Ident, *dst.FuncLit:
+		return true
+	case *dst.SelectorExpr:
+		return isPure(v.X)
+	case *dst.ParenExpr:
+		return isPure(v.X)
+	case *dst.UnaryExpr:
+		return v.Op != token.ARROW && isPure(v.X)
+	case *dst.CompositeLit:
+		for _, e := range v.Elts {
+			if kv, ok := e.(*dst.KeyValueExpr); ok {
+				if !isPure(kv.Key) || !isPure(kv.Value) {
+					return false
+				}
+			} else if !isPure(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isConstKeyedMapLit reports whether lit is a map literal where all keys are
+// constant literals of the same kind, but imaginary, which do not order.
+func isConstKeyedMapLit(lit *dst.CompositeLit) bool {
+	if _, ok := lit.Type.(*dst.MapType); !ok {
+		return false
+	}
+
+	if len(lit.Elts) < 2 {
+		return false
+	}
+
+	var kind token.Token
+	for i, e := range lit.Elts {
+		kv, ok := e.(*dst.KeyValueExpr)
+		if !ok {
+			return false
+		}
+		b, ok := kv.Key.(*dst.BasicLit)
+		if !ok || b.Kind == token.IMAG {
+			return false
+		}
+		if i == 0 {
+			kind = b.Kind
+		} else if b.Kind != kind {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortKeyedElts sorts the key/value elements of a struct literal by field name.
+func sortKeyedElts(elts []dst.Expr) {
+	sortElts(elts, func(e1, e2 dst.Expr) bool {
+		return e1.(*dst.KeyValueExpr).Key.(*dst.Ident).Name < e2.(*dst.KeyValueExpr).Key.(*dst.Ident).Name
+	})
+}
+
+// sortMapElts sorts the entries of a map literal by the value of their
+// constant keys, so e.g. 10 sorts after 9.
+func sortMapElts(elts []dst.Expr) {
+	value := func(e dst.Expr) constant.Value {
+		b := e.(*dst.KeyValueExpr).Key.(*dst.BasicLit)
+		return constant.MakeFromLiteral(b.Value, b.Kind, 0)
+	}
+
+	sortElts(elts, func(e1, e2 dst.Expr) bool {
+		v1, v2 := value(e1), value(e2)
+		if v1.Kind() == constant.Unknown || v2.Kind() == constant.Unknown {
+			return false
+		}
+		return constant.Compare(v1, token.LSS, v2)
+	})
+}
+
+// sortElts sorts elts using less. Comments travel with their element,
+// but the line spacing stays where it was, so single line literals stay on
+// one line and blank line separators are kept in place.
+func sortElts(elts []dst.Expr, less func(e1, e2 dst.Expr) bool) {
+	type spacing struct {
+		before, after dst.SpaceType
+	}
+
+	spacings := make([]spacing, len(elts))
+	for i, e := range elts {
+		decs := e.Decorations()
+		spacings[i] = spacing{before: decs.Before, after: decs.After}
+	}
+
+	sort.SliceStable(elts, func(i, j int) bool {
+		return less(elts[i], elts[j])
+	})
+
+	for i, e := range elts {
+		decs := e.Decorations()
+		decs.Before, decs.After = spacings[i].before, spacings[i].after
+	}
+}
@@ -0,0 +1,121 @@
+package gorder
+
+import "testing"
+
+func TestStructLiterals(t *testing.T) {
+	for _, test := range []struct {
+		name, src, want string
+	}{
+		{
+			"values free of side effects",
+			`package p
+
+var v = T{z: 1, b: x.y, c: func() {}, d: &U{q: -1}, a: "a"}
+`,
+			`package p
+
+var v = T{a: "a", b: x.y, c: func() {}, d: &U{q: -1}, z: 1}
+`,
+		},
+		{
+			"call",
+			`package p
+
+var v = T{z: next(), b: 2}
+`,
+			`package p
+
+var v = T{z: next(), b: 2}
+`,
+		},
+		{
+			"call in a nested literal",
+			`package p
+
+var v = T{z: U{q: next()}, b: 2}
+`,
+			`package p
+
+var v = T{z: U{q: next()}, b: 2}
+`,
+		},
+		{
+			"channel receive",
+			`package p
+
+var v = T{z: <-ch, b: 1}
+`,
+			`package p
+
+var v = T{z: <-ch, b: 1}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.StructLiterals = true
+			if got := sortSource(t, test.src, opts); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMapLiterals(t *testing.T) {
+	for _, test := range []struct {
+		name, src, want string
+	}{
+		{
+			"int keys",
+			`package p
+
+var v = map[int]string{10: "ten", 9: "nine", 1: "one"}
+`,
+			`package p
+
+var v = map[int]string{1: "one", 9: "nine", 10: "ten"}
+`,
+		},
+		{
+			"string keys",
+			`package p
+
+var v = map[string]int{"b": 2, "a": 1}
+`,
+			`package p
+
+var v = map[string]int{"a": 1, "b": 2}
+`,
+		},
+		{
+			"complex keys",
+			`package p
+
+var v = map[complex128]int{2i: 1, 1i: 2}
+`,
+			`package p
+
+var v = map[complex128]int{2i: 1, 1i: 2}
+`,
+		},
+		{
+			"keys of different kinds",
+			`package p
+
+var v = map[float64]int{2.5: 1, 1: 2}
+`,
+			`package p
+
+var v = map[float64]int{2.5: 1, 1: 2}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.MapLiterals = true
+			if got := sortSource(t, test.src, opts); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
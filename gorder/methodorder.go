@@ -0,0 +1,93 @@
+package gorder
+
+import (
+	"github.com/dave/dst"
+)
+
+// methodIndex looks up the position of methods with a required order.
+type methodIndex map[string]map[string]int
+
+func newMethodIndex(order map[string][]string) methodIndex {
+	m := make(methodIndex)
+	for recv, names := range order {
+		m[recv] = make(map[string]int)
+		for i, name := range names {
+			if _, found := m[recv][name]; !found {
+				m[recv][name] = i
+			}
+		}
+	}
+	return m
+}
+
+// less compares two sort names on the form receiver.method. It reports
+// whether s1 goes before s2 and whether the required order decides that,
+// which is when both are methods on the same receiver and at least one of
+// them has a required position. Methods with a position go before those
+// without; the type itself is never decided here so it stays above all of
+// its methods.
+func (m methodIndex) less(s1, s2 string) (bool, bool) {
+	r1, m1 := splitOnDot(s1)
+	r2, m2 := splitOnDot(s2)
+	if r1 == "" || r1 != r2 || m1 == magicTypeMarker || m2 == magicTypeMarker {
+		return false, false
+	}
+
+	i1, ok1 := m[r1][m1]
+	i2, ok2 := m[r2][m2]
+	switch {
+	case ok1 && ok2:
+		return i1 < i2, i1 != i2
+	case ok1 || ok2:
+		return ok1, true
+	}
+
+	return false, false
+}
+
+// declIndex looks up the position of top-level declarations with a required
+// order, by name.
+type declIndex map[string]int
+
+func newDeclIndex(order []string) declIndex {
+	m := make(declIndex)
+	for i, name := range order {
+		if _, found := m[name]; !found {
+			m[name] = i
+		}
+	}
+	return m
+}
+
+// index returns the position of d in the required order, or -1 if it has
+// none. Functions and types are looked up by name, methods as T.Method and
+// var and const declarations by their first name.
+func (m declIndex) index(d dst.Decl) int {
+	if len(m) == 0 {
+		return -1
+	}
+
+	var name string
+	switch v := d.(type) {
+	case *dst.FuncDecl:
+		name = v.Name.Name
+		if recv := fieldListName(v.Recv); recv != "" {
+			name = recv + "." + name
+		}
+	case *dst.GenDecl:
+		if len(v.Specs) == 0 {
+			return -1
+		}
+		switch s := v.Specs[0].(type) {
+		case *dst.TypeSpec:
+			name = s.Name.Name
+		case *dst.ValueSpec:
+			name = s.Names[0].Name
+		}
+	}
+
+	if i, ok := m[name]; ok {
+		return i
+	}
+	return -1
+}
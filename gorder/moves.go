@@ -0,0 +1,86 @@
+package gorder
+
+import (
+	"go/parser"
+	"go/token"
+
+	"github.com/dave/dst"
+)
+
+// Move describes a top-level declaration that changed position.
+type Move struct {
+	// Name is the declaration's name. Multiple names, as in var a, b, are
+	// comma separated.
+	Name string `json:"name"`
+
+	// Kind is one of func, method, type, const, var or import.
+	Kind string `json:"kind"`
+
+	// Receiver is the receiver type of a method.
+	Receiver string `json:"receiver,omitempty"`
+
+	// The index among the top-level declarations before and after.
+	OldIndex int `json:"oldIndex"`
+	NewIndex int `json:"newIndex"`
+
+	// The line of the declaration, not counting its doc comment, before
+	// and after.
+	OldLine int `json:"oldLine"`
+	NewLine int `json:"newLine"`
+}
+
+// declMoves returns the declarations in before that are at another index in
+// after. oldLines holds their lines in the source.
+func declMoves(before, after []dst.Decl, oldLines map[dst.Decl]int) []Move {
+	newIndex := make(map[dst.Decl]int, len(after))
+	for i, d := range after {
+		newIndex[d] = i
+	}
+
+	var moves []Move
+	for i, d := range before {
+		j, ok := newIndex[d]
+		if !ok || i == j {
+			continue
+		}
+		kind, recv, name := declName(d)
+		moves = append(moves, Move{
+			Name:     name,
+			Kind:     kind,
+			Receiver: recv,
+			OldIndex: i,
+			NewIndex: j,
+			OldLine:  oldLines[d],
+		})
+	}
+
+	return moves
+}
+
+// moveLines sets the NewLine of moves, computed from after, from the
+// printed source src of decls. It does nothing if AfterSort added or
+// removed declarations.
+func moveLines(moves []Move, after, decls []dst.Decl, src []byte) error {
+	if len(moves) == 0 || len(after) != len(decls) {
+		return nil
+	}
+	for i := range after {
+		if after[i] != decls[i] {
+			return nil
+		}
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return err
+	}
+	if len(file.Decls) != len(decls) {
+		return nil
+	}
+	for i := range moves {
+		moves[i].NewLine = fset.Position(file.Decls[moves[i].NewIndex].Pos()).Line
+	}
+
+	return nil
+}
@@ -0,0 +1,345 @@
+package gorder
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/dave/dst"
+)
+
+// Orderer reorders Go source with a fixed set of options. It is safe for
+// concurrent use.
+type Orderer struct {
+	opts Options
+}
+
+// Option configures an Orderer.
+type Option func(o *Options)
+
+// New creates an Orderer with DefaultOptions, as used by the gorder
+// command, adjusted by opts.
+func New(opts ...Option) (*Orderer, error) {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &Orderer{opts: o}, nil
+}
+
+// Options returns the options used by o.
+func (o *Orderer) Options() Options {
+	return o.opts
+}
+
+// Process reorders the Go source src; see Process.
+func (o *Orderer) Process(src []byte) ([]byte, error) {
+	return Process(src, o.opts)
+}
+
+// Order reads Go source from r and writes it reordered to w; see Order.
+func (o *Orderer) Order(w io.Writer, r io.Reader) error {
+	return Order(w, r, o.opts)
+}
+
+// ProcessFile reads and reorders the Go source file filename; see
+// ProcessFile.
+func (o *Orderer) ProcessFile(filename string) ([]byte, error) {
+	return ProcessFile(filename, o.opts)
+}
+
+// Reorder reorders the Go source src of the file filename; see Reorder.
+func (o *Orderer) Reorder(filename string, src []byte) (Result, error) {
+	return Reorder(filename, src, o.opts)
+}
+
+// ReorderContext is like Reorder, but stops when ctx is done; see
+// ReorderContext.
+func (o *Orderer) ReorderContext(ctx context.Context, filename string, src []byte) (Result, error) {
+	return ReorderContext(ctx, filename, src, o.opts)
+}
+
+// Explain returns how the declarations in src are ranked; see Explain.
+func (o *Orderer) Explain(src []byte) ([]Explanation, error) {
+	return Explain(src, o.opts)
+}
+
+// ReorderFS reorders the Go files in fsys matching pattern; see ReorderFS.
+func (o *Orderer) ReorderFS(fsys fs.FS, pattern string) (map[string]Result, error) {
+	return ReorderFS(fsys, pattern, o.opts)
+}
+
+// ReorderFSFunc reorders the Go files in fsys matching pattern, calling fn
+// with each result; see ReorderFSFunc.
+func (o *Orderer) ReorderFSFunc(fsys fs.FS, pattern string, fn func(name string, r Result) error) error {
+	return ReorderFSFunc(fsys, pattern, o.opts, fn)
+}
+
+// WithOptions replaces all options with opts.
+func WithOptions(opts Options) Option {
+	return func(o *Options) {
+		*o = opts
+	}
+}
+
+// WithMode sets the placement strategy, one of ModeDefault, ModeCaller or
+// ModeAlpha.
+func WithMode(mode string) Option {
+	return func(o *Options) {
+		o.Mode = mode
+	}
+}
+
+// WithWeights sets the section weights.
+func WithWeights(w Weights) Option {
+	return func(o *Options) {
+		o.Weights = w
+	}
+}
+
+// WithPrefixes sets the name prefixes ignored when comparing names.
+func WithPrefixes(prefixes ...string) Option {
+	return func(o *Options) {
+		o.Prefixes = prefixes
+	}
+}
+
+// WithKinds sets the declaration kinds to sort.
+func WithKinds(k Kind) Option {
+	return func(o *Options) {
+		o.Kinds = k
+	}
+}
+
+// WithEmbedded sets the placement of embedded fields, one of EmbeddedFirst,
+// EmbeddedLast or EmbeddedMixed.
+func WithEmbedded(embedded string) Option {
+	return func(o *Options) {
+		o.Embedded = embedded
+	}
+}
+
+// WithStructFieldSorting enables or disables sorting of struct fields.
+func WithStructFieldSorting(on bool) Option {
+	return func(o *Options) {
+		o.StructFields = on
+		if on {
+			o.Kinds |= KindStruct
+		}
+	}
+}
+
+// WithStructLiteralSorting enables or disables sorting of the fields in
+// keyed struct literals.
+func WithStructLiteralSorting(on bool) Option {
+	return func(o *Options) {
+		o.StructLiterals = on
+	}
+}
+
+// WithMapLiteralSorting enables or disables sorting of the entries in map
+// literals with constant keys.
+func WithMapLiteralSorting(on bool) Option {
+	return func(o *Options) {
+		o.MapLiterals = on
+	}
+}
+
+// WithSize sets the size tiebreaker, one of SizeNone, SizeAsc or SizeDesc.
+func WithSize(size string) Option {
+	return func(o *Options) {
+		o.Size = size
+	}
+}
+
+// WithMinimal enables or disables moving as few declarations as possible.
+func WithMinimal(on bool) Option {
+	return func(o *Options) {
+		o.Minimal = on
+	}
+}
+
+// WithInsertOnly enables or disables only moving declarations added since
+// the git HEAD version of the file.
+func WithInsertOnly(on bool) Option {
+	return func(o *Options) {
+		o.InsertOnly = on
+	}
+}
+
+// WithChangedLines sets the changed lines, only moving the declarations
+// overlapping them.
+func WithChangedLines(lines ...LineRange) Option {
+	return func(o *Options) {
+		if lines == nil {
+			lines = []LineRange{}
+		}
+		o.ChangedLines = lines
+	}
+}
+
+// WithExportedTypesFirst enables or disables placing exported types before
+// unexported ones.
+func WithExportedTypesFirst(on bool) Option {
+	return func(o *Options) {
+		o.ExportedTypesFirst = on
+	}
+}
+
+// WithErrors sets the placement of the errors section, one of ErrorsNone,
+// ErrorsTop or ErrorsBottom.
+func WithErrors(errors string) Option {
+	return func(o *Options) {
+		o.Errors = errors
+	}
+}
+
+// WithFlagsNearMain enables or disables placing flag vars right before func
+// main.
+func WithFlagsNearMain(on bool) Option {
+	return func(o *Options) {
+		o.FlagsNearMain = on
+	}
+}
+
+// WithPairs sets the groups of method names kept together, e.g. Open and
+// Close.
+func WithPairs(pairs [][]string) Option {
+	return func(o *Options) {
+		o.Pairs = pairs
+	}
+}
+
+// WithNormalizeSpace enables or disables separating top-level declarations
+// by exactly one blank line.
+func WithNormalizeSpace(on bool) Option {
+	return func(o *Options) {
+		o.NormalizeSpace = on
+	}
+}
+
+// WithBanners enables or disables section banner comments.
+func WithBanners(on bool) Option {
+	return func(o *Options) {
+		o.Banners = on
+	}
+}
+
+// WithOutline enables or disables the outline comment.
+func WithOutline(on bool) Option {
+	return func(o *Options) {
+		o.Outline = on
+	}
+}
+
+// WithGRPC enables or disables ordering gRPC handler methods as in the
+// service interface.
+func WithGRPC(on bool) Option {
+	return func(o *Options) {
+		o.GRPC = on
+	}
+}
+
+// WithFuncVars enables or disables sorting vars holding functions as
+// functions.
+func WithFuncVars(on bool) Option {
+	return func(o *Options) {
+		o.FuncVars = on
+	}
+}
+
+// WithDirectives enables or disables the //gorder: comment directives.
+func WithDirectives(on bool) Option {
+	return func(o *Options) {
+		o.Directives = on
+	}
+}
+
+// WithConstructors sets the name prefixes of constructor functions.
+func WithConstructors(prefixes ...string) Option {
+	return func(o *Options) {
+		o.Constructors = prefixes
+	}
+}
+
+// WithCtorReturn enables or disables treating functions returning a type
+// declared in the file as constructors.
+func WithCtorReturn(on bool) Option {
+	return func(o *Options) {
+		o.CtorReturn = on
+	}
+}
+
+// WithMethodsAsFuncs enables or disables sorting methods among the plain
+// functions.
+func WithMethodsAsFuncs(on bool) Option {
+	return func(o *Options) {
+		o.MethodsAsFuncs = on
+	}
+}
+
+// WithLang sets the Go language version the source targets, e.g. go1.21.
+func WithLang(lang string) Option {
+	return func(o *Options) {
+		o.Lang = lang
+	}
+}
+
+// WithStubs sets the placement of functions without a body and
+// declarations with a //go:linkname directive, one of StubsKeep, StubsGroup
+// or StubsSort.
+func WithStubs(policy string) Option {
+	return func(o *Options) {
+		o.Stubs = policy
+	}
+}
+
+// WithFloatingComments sets the placement of free-floating comments, one of
+// FloatingNext, FloatingPrevious or FloatingKeep.
+func WithFloatingComments(policy string) Option {
+	return func(o *Options) {
+		o.FloatingComments = policy
+	}
+}
+
+// WithSelfCheck enables or disables checking that sorting the sorted source
+// again changes nothing.
+func WithSelfCheck(on bool) Option {
+	return func(o *Options) {
+		o.SelfCheck = on
+	}
+}
+
+// WithBeforeSort sets the hook called with the parsed file before sorting.
+func WithBeforeSort(fn func(file *dst.File) error) Option {
+	return func(o *Options) {
+		o.BeforeSort = fn
+	}
+}
+
+// WithAfterSort sets the hook called with the sorted file and its moves
+// before printing.
+func WithAfterSort(fn func(file *dst.File, moves []Move) error) Option {
+	return func(o *Options) {
+		o.AfterSort = fn
+	}
+}
+
+// WithImports enables organizing the imports, with the local imports
+// matching prefixes grouped last.
+func WithImports(on bool, prefixes ...string) Option {
+	return func(o *Options) {
+		o.Imports = on
+		o.LocalPrefixes = prefixes
+	}
+}
+
+// WithFormat sets the function formatting the sorted source.
+func WithFormat(fn func(src []byte) ([]byte, error)) Option {
+	return func(o *Options) {
+		o.Format = fn
+	}
+}
@@ -0,0 +1,105 @@
+package gorder
+
+import (
+	"go/token"
+
+	"github.com/dave/dst"
+)
+
+// The first and last lines of the outline comment block.
+const (
+	outlineBegin = "// Outline (maintained by gorder):"
+	outlineEnd   = "// End of outline."
+)
+
+// addOutline adds an outline of the types and functions in decls, in their
+// current order and with methods listed below their type, to the first
+// declaration after the imports.
+func addOutline(decls []dst.Decl) {
+	types := make(map[string]bool)
+	for _, d := range decls {
+		if g, ok := d.(*dst.GenDecl); ok && g.Tok == token.TYPE {
+			for _, spec := range g.Specs {
+				types[spec.(*dst.TypeSpec).Name.Name] = true
+			}
+		}
+	}
+
+	methods := make(map[string][]string)
+	for _, d := range decls {
+		if f, ok := d.(*dst.FuncDecl); ok && f.Recv != nil {
+			recv := fieldListName(f.Recv)
+			if types[recv] {
+				methods[recv] = append(methods[recv], f.Name.Name)
+			}
+		}
+	}
+
+	var lines []string
+	for _, d := range decls {
+		switch v := d.(type) {
+		case *dst.FuncDecl:
+			if v.Recv == nil {
+				lines = append(lines, "//\tfunc "+v.Name.Name)
+				continue
+			}
+			if recv := fieldListName(v.Recv); !types[recv] {
+				lines = append(lines, "//\tfunc ("+recv+") "+v.Name.Name)
+			}
+		case *dst.GenDecl:
+			if v.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range v.Specs {
+				name := spec.(*dst.TypeSpec).Name.Name
+				lines = append(lines, "//\ttype "+name)
+				for _, m := range methods[name] {
+					lines = append(lines, "//\t\t"+m)
+				}
+			}
+		}
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+
+	for _, d := range decls {
+		if preserveOrder(d) {
+			continue
+		}
+		block := append([]string{outlineBegin, "//"}, lines...)
+		block = append(block, outlineEnd, "\n")
+		d.Decorations().Start.Prepend(block...)
+		if d.Decorations().Before == dst.None || d.Decorations().Before == dst.NewLine {
+			d.Decorations().Before = dst.EmptyLine
+		}
+		return
+	}
+}
+
+// removeOutline removes the outline added by addOutline.
+func removeOutline(decls []dst.Decl) {
+	for _, d := range decls {
+		decs := d.Decorations()
+		var (
+			start   []string
+			inBlock bool
+		)
+		for i := 0; i < len(decs.Start); i++ {
+			dec := decs.Start[i]
+			switch {
+			case dec == outlineBegin:
+				inBlock = true
+			case inBlock && dec == outlineEnd:
+				inBlock = false
+				if i+1 < len(decs.Start) && decs.Start[i+1] == "\n" {
+					i++
+				}
+			case !inBlock:
+				start = append(start, dec)
+			}
+		}
+		decs.Start.Replace(start...)
+	}
+}
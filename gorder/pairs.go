@@ -0,0 +1,97 @@
+package gorder
+
+import (
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// DefaultPairs are the conventional lifecycle method groups.
+const DefaultPairs = "Open:Close,Start:Stop,Lock:Unlock,RLock:RUnlock,Begin:Commit:Rollback"
+
+// ParsePairs parses groups on the form "Open:Close,Start:Stop".
+func ParsePairs(s string) [][]string {
+	var groups [][]string
+	for _, g := range strings.Split(s, ",") {
+		var group []string
+		for _, name := range strings.Split(g, ":") {
+			if name = strings.TrimSpace(name); name != "" {
+				group = append(group, name)
+			}
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// pairNamer maps the names of methods that belong to a lifecycle group to
+// the name of the group's first method.
+type pairNamer struct {
+	// Method name to its group and position in the group.
+	groups map[string]pairPos
+
+	// Method names declared per receiver.
+	methods map[string]map[string]bool
+}
+
+type pairPos struct {
+	group []string
+	index int
+}
+
+func newPairNamer(groups [][]string, decls []dst.Decl) pairNamer {
+	p := pairNamer{
+		groups:  make(map[string]pairPos),
+		methods: make(map[string]map[string]bool),
+	}
+
+	if len(groups) == 0 {
+		return p
+	}
+
+	for _, g := range groups {
+		for i, name := range g {
+			p.groups[name] = pairPos{group: g, index: i}
+		}
+	}
+
+	for _, d := range decls {
+		f, ok := d.(*dst.FuncDecl)
+		if !ok || f.Recv == nil {
+			continue
+		}
+		recv := fieldListName(f.Recv)
+		if p.methods[recv] == nil {
+			p.methods[recv] = make(map[string]bool)
+		}
+		p.methods[recv][f.Name.Name] = true
+	}
+
+	return p
+}
+
+// name takes a sort name on the form receiver.method and, if the method
+// follows an earlier method of its group declared on the same receiver,
+// returns the sort name of the group's first declared method along with the
+// method's position in the group.
+func (p pairNamer) name(s string) (string, int) {
+	recv, method := splitOnDot(s)
+	if recv == "" {
+		return s, 0
+	}
+
+	pos, ok := p.groups[method]
+	if !ok {
+		return s, 0
+	}
+
+	for _, lead := range pos.group[:pos.index] {
+		if p.methods[recv][lead] {
+			return recv + "." + lead, pos.index
+		}
+	}
+
+	return s, pos.index
+}
@@ -0,0 +1,60 @@
+package gorder
+
+import "testing"
+
+func TestPairs(t *testing.T) {
+	src := `package p
+
+type F struct{}
+
+func (F) Open() {}
+
+func (F) Name() {}
+
+func (F) Close() {}
+`
+	for _, test := range []struct {
+		name  string
+		pairs [][]string
+		want  string
+	}{
+		{
+			"none by default",
+			nil,
+			`package p
+
+type F struct{}
+
+func (F) Close() {}
+
+func (F) Name() {}
+
+func (F) Open() {}
+`,
+		},
+		{
+			"default pairs",
+			ParsePairs(DefaultPairs),
+			`package p
+
+type F struct{}
+
+func (F) Name() {}
+
+func (F) Open() {}
+
+func (F) Close() {}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if test.pairs != nil {
+				opts.Pairs = test.pairs
+			}
+			if got := sortSource(t, src, opts); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
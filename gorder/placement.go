@@ -0,0 +1,351 @@
+package gorder
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// placeHelpers rearranges the already sorted decls so that every unexported
+// helper function follows immediately after the first function calling it,
+// giving a top-down reading order. Helpers called from several places stay
+// with their first caller, their own helpers follow them recursively, and
+// helpers not reachable from any other function keep their sorted position.
+// Of the helpers only calling each other in a cycle, the first in original,
+// the source order, keeps its position and the others follow it.
+func placeHelpers(decls, original []dst.Decl) error {
+	helpers := make(map[string]*dst.FuncDecl)
+	for _, d := range decls {
+		if f, ok := d.(*dst.FuncDecl); ok && isHelper(f) {
+			helpers[f.Name.Name] = f
+		}
+	}
+
+	if len(helpers) == 0 {
+		return nil
+	}
+
+	// The helpers called by each function, in order of appearance.
+	callees := make(map[dst.Decl][]*dst.FuncDecl)
+	called := make(map[*dst.FuncDecl]bool)
+	for _, d := range decls {
+		f, ok := d.(*dst.FuncDecl)
+		if !ok || f.Body == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		dst.Inspect(f.Body, func(n dst.Node) bool {
+			call, ok := n.(*dst.CallExpr)
+			if !ok {
+				return true
+			}
+			id, ok := call.Fun.(*dst.Ident)
+			if !ok || id.Path != "" || seen[id.Name] {
+				return true
+			}
+			if h, ok := helpers[id.Name]; ok && h != f {
+				seen[id.Name] = true
+				callees[d] = append(callees[d], h)
+				called[h] = true
+			}
+			return true
+		})
+	}
+
+	// Helpers are moved if they can be reached from a function that keeps
+	// its position, a root.
+	reachable := make(map[dst.Decl]bool)
+	roots := make(map[dst.Decl]bool)
+	var mark func(d dst.Decl)
+	mark = func(d dst.Decl) {
+		for _, h := range callees[d] {
+			if !reachable[h] && !roots[h] {
+				reachable[h] = true
+				mark(h)
+			}
+		}
+	}
+	for _, d := range decls {
+		if f, ok := d.(*dst.FuncDecl); !ok || !called[f] {
+			roots[d] = true
+		}
+	}
+	for _, d := range decls {
+		if roots[d] {
+			mark(d)
+		}
+	}
+
+	// The helpers left are only called in cycles among themselves, or by
+	// such helpers. Each cycle not called from outside it gets a root.
+	var cycles []dst.Decl
+	for _, d := range original {
+		if f, ok := d.(*dst.FuncDecl); ok && called[f] && !reachable[d] {
+			cycles = append(cycles, d)
+		}
+	}
+	for _, d := range cycleRoots(cycles, callees) {
+		roots[d] = true
+		mark(d)
+	}
+
+	result := make([]dst.Decl, 0, len(decls))
+	placed := make(map[dst.Decl]bool)
+	var place func(d dst.Decl)
+	place = func(d dst.Decl) {
+		placed[d] = true
+		result = append(result, d)
+		for _, h := range callees[d] {
+			if !placed[h] {
+				place(h)
+			}
+		}
+	}
+
+	for _, d := range decls {
+		if placed[d] || reachable[d] {
+			continue
+		}
+		place(d)
+	}
+
+	if len(result) != len(decls) {
+		return fmt.Errorf("placing the helpers below their callers left %d of %d declarations", len(result), len(decls))
+	}
+	copy(decls, result)
+	return nil
+}
+
+// cycleRoots returns the first declaration, in the order of decls, of each
+// strongly connected component of the call graph of decls, given by
+// callees, that no declaration outside it calls.
+func cycleRoots(decls []dst.Decl, callees map[dst.Decl][]*dst.FuncDecl) []dst.Decl {
+	in := make(map[dst.Decl]bool, len(decls))
+	for _, d := range decls {
+		in[d] = true
+	}
+
+	// Tarjan's algorithm.
+	var (
+		index   = make(map[dst.Decl]int)
+		low     = make(map[dst.Decl]int)
+		onStack = make(map[dst.Decl]bool)
+		stack   []dst.Decl
+		comp    = make(map[dst.Decl]int)
+		ncomp   int
+	)
+	var visit func(d dst.Decl)
+	visit = func(d dst.Decl) {
+		index[d] = len(index) + 1
+		low[d] = index[d]
+		stack = append(stack, d)
+		onStack[d] = true
+		for _, h := range callees[d] {
+			if !in[h] {
+				continue
+			}
+			if index[h] == 0 {
+				visit(h)
+				low[d] = min(low[d], low[h])
+			} else if onStack[h] {
+				low[d] = min(low[d], index[h])
+			}
+		}
+		if low[d] == index[d] {
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				comp[top] = ncomp
+				if top == d {
+					break
+				}
+			}
+			ncomp++
+		}
+	}
+	for _, d := range decls {
+		if index[d] == 0 {
+			visit(d)
+		}
+	}
+
+	calledFromOutside := make(map[int]bool)
+	for _, d := range decls {
+		for _, h := range callees[d] {
+			if in[h] && comp[h] != comp[d] {
+				calledFromOutside[comp[h]] = true
+			}
+		}
+	}
+
+	var roots []dst.Decl
+	seen := make(map[int]bool)
+	for _, d := range decls {
+		if c := comp[d]; !calledFromOutside[c] && !seen[c] {
+			seen[c] = true
+			roots = append(roots, d)
+		}
+	}
+	return roots
+}
+
+// isHelper reports whether f is an unexported plain function that can be
+// moved below its caller.
+func isHelper(f *dst.FuncDecl) bool {
+	if f.Recv != nil {
+		return false
+	}
+	name := f.Name.Name
+	if name == "main" || name == "init" || name == "_" {
+		return false
+	}
+	return !token.IsExported(name)
+}
+
+// relocateMinimal returns the declarations in original rearranged so that
+// the longest subsequence already in the order given by sorted stays where it
+// is; every other declaration is moved to directly after its nearest
+// predecessor in sorted order.
+func relocateMinimal(original, sorted []dst.Decl) []dst.Decl {
+	rank := declRanks(sorted)
+
+	// Longest increasing subsequence of ranks, O(n log n).
+	var (
+		tails = make([]int, 0, len(original)) // Index into original of the smallest tail of each length.
+		prev  = make([]int, len(original))
+	)
+	for i, d := range original {
+		r := rank[d]
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			m := (lo + hi) / 2
+			if rank[original[tails[m]]] < r {
+				lo = m + 1
+			} else {
+				hi = m
+			}
+		}
+		if lo > 0 {
+			prev[i] = tails[lo-1]
+		} else {
+			prev[i] = -1
+		}
+		if lo == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[lo] = i
+		}
+	}
+
+	keep := make(map[dst.Decl]bool)
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i != -1; i = prev[i] {
+			keep[original[i]] = true
+		}
+	}
+
+	return relocate(original, sorted, keep)
+}
+
+// relocate keeps the declarations in keep in their original relative order
+// and inserts the others, in sorted order, directly after the kept
+// declaration ranked closest below them. Declarations ranked below all kept
+// declarations go first.
+func relocate(original, sorted []dst.Decl, keep map[dst.Decl]bool) []dst.Decl {
+	rank := declRanks(sorted)
+
+	// The moved declarations to insert after each kept declaration, nil
+	// being the start of the file.
+	after := make(map[dst.Decl][]dst.Decl)
+	for _, d := range sorted {
+		if keep[d] {
+			continue
+		}
+		var anchor dst.Decl
+		for _, k := range original {
+			if keep[k] && rank[k] < rank[d] && (anchor == nil || rank[k] > rank[anchor]) {
+				anchor = k
+			}
+		}
+		after[anchor] = append(after[anchor], d)
+	}
+
+	result := make([]dst.Decl, 0, len(original))
+	result = append(result, after[nil]...)
+	for _, d := range original {
+		if keep[d] {
+			result = append(result, d)
+			result = append(result, after[d]...)
+		}
+	}
+
+	return result
+}
+
+func declRanks(sorted []dst.Decl) map[dst.Decl]int {
+	rank := make(map[dst.Decl]int, len(sorted))
+	for i, d := range sorted {
+		rank[d] = i
+	}
+	return rank
+}
+
+// declKeys returns a key identifying each declaration across versions of the
+// same file, e.g. "func New", "method T.String" or "var a,b". Repeated keys,
+// such as multiple init funcs, get a running number appended.
+func declKeys(decls []dst.Decl) map[dst.Decl]string {
+	keys := make(map[dst.Decl]string, len(decls))
+	seen := make(map[string]int)
+	for _, d := range decls {
+		key := declKey(d)
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		keys[d] = key
+	}
+	return keys
+}
+
+func declKey(d dst.Decl) string {
+	kind, recv, name := declName(d)
+	if recv != "" {
+		name = recv + "." + name
+	}
+	if name == "" {
+		return kind
+	}
+	return kind + " " + name
+}
+
+// declName returns the kind of d, e.g. func, method or var, the receiver
+// type of a method and the name, with multiple names comma separated.
+func declName(d dst.Decl) (kind, recv, name string) {
+	switch v := d.(type) {
+	case *dst.FuncDecl:
+		if r := fieldListName(v.Recv); r != "" {
+			return "method", r, v.Name.Name
+		}
+		return "func", "", v.Name.Name
+	case *dst.GenDecl:
+		var names []string
+		for _, spec := range v.Specs {
+			switch s := spec.(type) {
+			case *dst.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *dst.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			case *dst.ImportSpec:
+				names = append(names, s.Path.Value)
+			}
+		}
+		return v.Tok.String(), "", strings.Join(names, ",")
+	default:
+		return fmt.Sprintf("%T", d), "", ""
+	}
+}
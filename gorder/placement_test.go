@@ -0,0 +1,214 @@
+package gorder
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlaceHelpers(t *testing.T) {
+	for _, test := range []struct {
+		name, src, want string
+	}{
+		{
+			"helpers below their first caller",
+			`package p
+
+func helper() {}
+
+func shared() {}
+
+func Zed() {
+	shared()
+}
+
+func Alpha() {
+	helper()
+	shared()
+}
+`,
+			`package p
+
+func Alpha() {
+	helper()
+	shared()
+}
+
+func helper() {}
+
+func shared() {}
+
+func Zed() {
+	shared()
+}
+`,
+		},
+		{
+			"cycle without outside callers rooted at its first decl",
+			`package p
+
+func pong() {
+	ping()
+}
+
+func ping() {
+	pong()
+}
+`,
+			`package p
+
+func pong() {
+	ping()
+}
+
+func ping() {
+	pong()
+}
+`,
+		},
+		{
+			"cycle rooted at its first decl in the source, not the sorted order",
+			`package p
+
+func zed() {
+	alpha()
+}
+
+func alpha() {
+	zed()
+}
+`,
+			`package p
+
+func zed() {
+	alpha()
+}
+
+func alpha() {
+	zed()
+}
+`,
+		},
+		{
+			"helper of a cycle declared before it",
+			`package p
+
+func log() {}
+
+func pong() {
+	ping()
+}
+
+func ping() {
+	pong()
+	log()
+}
+`,
+			`package p
+
+func pong() {
+	ping()
+}
+
+func ping() {
+	pong()
+	log()
+}
+
+func log() {}
+`,
+		},
+		{
+			"cycle called from another cycle",
+			`package p
+
+func even() {
+	odd()
+}
+
+func odd() {
+	even()
+	pong()
+}
+
+func pong() {
+	ping()
+}
+
+func ping() {
+	pong()
+}
+`,
+			`package p
+
+func even() {
+	odd()
+}
+
+func odd() {
+	even()
+	pong()
+}
+
+func pong() {
+	ping()
+}
+
+func ping() {
+	pong()
+}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Mode = ModeCaller
+			got := sortSource(t, test.src, opts)
+			if got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+			if again := sortSource(t, got, opts); again != got {
+				t.Errorf("sorting again changes it:\n%s", again)
+			}
+		})
+	}
+}
+
+// TestIdempotentCorpus sorts the large files of the benchmark corpus in
+// every mode and checks that sorting the result again leaves it as is.
+func TestIdempotentCorpus(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow")
+	}
+	filenames, err := filepath.Glob(filepath.Join("..", "testdata", "corpus", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filenames) == 0 {
+		t.Fatal("no files in ../testdata/corpus")
+	}
+	for _, mode := range []string{ModeDefault, ModeCaller, ModeAlpha, ModeGodoc} {
+		for _, filename := range filenames {
+			t.Run(mode+"/"+filepath.Base(filename), func(t *testing.T) {
+				src, err := os.ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts := DefaultOptions()
+				opts.Mode = mode
+				r, err := Reorder(filename, src, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				again, err := Reorder(filename, r.Src, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(again.Src, r.Src) {
+					t.Errorf("sorting again changes the file from line %d", firstDiffLine(r.Src, again.Src))
+				}
+			})
+		}
+	}
+}
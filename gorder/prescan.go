@@ -0,0 +1,266 @@
+package gorder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+
+	"github.com/dave/dst"
+)
+
+// prescan reports whether src is already in order without decorating it,
+// which is the expensive part of a run. It parses src with go/parser,
+// converts the syntax tree to a dst file without the comments and sorts
+// that. ok is false if src needs the full round trip, i.e. if it may change
+// or uses options depending on the comments or the printed layout.
+func prescan(ctx context.Context, filename string, src []byte, opts Options) (res Result, ok bool) {
+	if !prescannable(src, opts) {
+		return Result{}, false
+	}
+
+	fset := token.NewFileSet()
+	af, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return Result{}, false
+	}
+
+	// The file must format as is, or the round trip would change it even if
+	// the order does not.
+	b := getBuffer(len(src))
+	err = format.Node(b, fset, af)
+	formatted := err == nil && bytes.Equal(b.Bytes(), src)
+	putBuffer(b)
+	if !formatted {
+		return Result{}, false
+	}
+
+	file, err := convertFile(af)
+	if err != nil {
+		return Result{}, false
+	}
+
+	before := orderSnapshot(file)
+	if err := sortFile(ctx, filename, file, nil, nil, opts); err != nil {
+		return Result{}, false
+	}
+	if !equalSnapshots(before, orderSnapshot(file)) {
+		return Result{}, false
+	}
+
+	if opts.Lang != "" {
+		if err := checkLang(fset, af, opts.Lang); err != nil {
+			return Result{}, false
+		}
+	}
+
+	if opts.Format != nil {
+		formatted, err := opts.Format(src)
+		if err != nil || !bytes.Equal(formatted, src) {
+			return Result{}, false
+		}
+	}
+
+	keys := declKeys(file.Decls)
+	order := make([]string, len(file.Decls))
+	for i, d := range file.Decls {
+		order[i] = keys[d]
+	}
+
+	return Result{Src: src, Order: order}, true
+}
+
+// prescannable reports whether the result of sorting src with opts only
+// depends on the syntax tree without the comments.
+func prescannable(src []byte, opts Options) bool {
+	switch {
+	case opts.Size != SizeNone, opts.InsertOnly, opts.ChangedLines != nil, opts.GRPC, opts.Align != nil,
+		opts.Banners, opts.Outline, opts.NormalizeSpace, opts.Imports,
+		opts.BeforeSort != nil, opts.AfterSort != nil:
+		return false
+	}
+	return !HasLineDirectives(src) &&
+		!bytes.Contains(src, []byte(DirectivePrefix)) &&
+		!bytes.Contains(src, []byte("go:linkname"))
+}
+
+// orderSnapshot returns the elements of the lists in file that sorting may
+// reorder, each list preceded by its length.
+func orderSnapshot(file *dst.File) []any {
+	var s []any
+	dst.Inspect(file, func(n dst.Node) bool {
+		switch v := n.(type) {
+		case *dst.File:
+			s = append(s, len(v.Decls))
+			for _, d := range v.Decls {
+				s = append(s, d)
+			}
+		case *dst.GenDecl:
+			s = append(s, len(v.Specs))
+			for _, spec := range v.Specs {
+				s = append(s, spec)
+			}
+		case *dst.FieldList:
+			s = append(s, len(v.List))
+			for _, f := range v.List {
+				s = append(s, f)
+			}
+		case *dst.CompositeLit:
+			s = append(s, len(v.Elts))
+			for _, e := range v.Elts {
+				s = append(s, e)
+			}
+		}
+		return true
+	})
+	return s
+}
+
+func equalSnapshots(a, b []any) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// dstTypes maps the names of the ast node types to their dst counterparts.
+var dstTypes = func() map[string]reflect.Type {
+	m := make(map[string]reflect.Type)
+	for _, n := range []dst.Node{
+		(*dst.ArrayType)(nil), (*dst.AssignStmt)(nil), (*dst.BasicLit)(nil),
+		(*dst.BinaryExpr)(nil), (*dst.BlockStmt)(nil), (*dst.BranchStmt)(nil),
+		(*dst.CallExpr)(nil), (*dst.CaseClause)(nil), (*dst.ChanType)(nil),
+		(*dst.CommClause)(nil), (*dst.CompositeLit)(nil), (*dst.DeclStmt)(nil),
+		(*dst.DeferStmt)(nil), (*dst.Ellipsis)(nil), (*dst.EmptyStmt)(nil),
+		(*dst.ExprStmt)(nil), (*dst.Field)(nil), (*dst.FieldList)(nil),
+		(*dst.File)(nil), (*dst.ForStmt)(nil), (*dst.FuncDecl)(nil),
+		(*dst.FuncLit)(nil), (*dst.FuncType)(nil), (*dst.GenDecl)(nil),
+		(*dst.GoStmt)(nil), (*dst.Ident)(nil), (*dst.IfStmt)(nil),
+		(*dst.ImportSpec)(nil), (*dst.IncDecStmt)(nil), (*dst.IndexExpr)(nil),
+		(*dst.IndexListExpr)(nil), (*dst.InterfaceType)(nil), (*dst.KeyValueExpr)(nil),
+		(*dst.LabeledStmt)(nil), (*dst.MapType)(nil), (*dst.ParenExpr)(nil),
+		(*dst.RangeStmt)(nil), (*dst.ReturnStmt)(nil), (*dst.SelectStmt)(nil),
+		(*dst.SelectorExpr)(nil), (*dst.SendStmt)(nil), (*dst.SliceExpr)(nil),
+		(*dst.StarExpr)(nil), (*dst.StructType)(nil), (*dst.SwitchStmt)(nil),
+		(*dst.TypeAssertExpr)(nil), (*dst.TypeSpec)(nil), (*dst.TypeSwitchStmt)(nil),
+		(*dst.UnaryExpr)(nil), (*dst.ValueSpec)(nil),
+	} {
+		t := reflect.TypeOf(n).Elem()
+		m[t.Name()] = t
+	}
+	return m
+}()
+
+var (
+	posType  = reflect.TypeOf(token.NoPos)
+	nodeType = reflect.TypeOf((*ast.Node)(nil)).Elem()
+)
+
+// convertFile converts f to a dst file without the comments and positions.
+func convertFile(f *ast.File) (file *dst.File, err error) {
+	c := converter{nodes: make(map[ast.Node]reflect.Value)}
+	v, err := c.node(reflect.ValueOf(f))
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface().(*dst.File), nil
+}
+
+type converter struct {
+	// nodes holds the nodes converted, so nodes shared in the ast file,
+	// e.g. the imports, are shared in the dst file.
+	nodes map[ast.Node]reflect.Value
+}
+
+// node converts the ast node pointer v to a pointer to the dst node of the
+// same name.
+func (c converter) node(v reflect.Value) (reflect.Value, error) {
+	n := v.Interface().(ast.Node)
+	if d, ok := c.nodes[n]; ok {
+		return d, nil
+	}
+
+	src := v.Elem()
+	t, ok := dstTypes[src.Type().Name()]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unsupported node %T", n)
+	}
+	d := reflect.New(t)
+	c.nodes[n] = d
+
+	dv := d.Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch f.Name {
+		case "Decs", "Obj", "Scope", "Unresolved":
+			continue
+		}
+		sf := src.FieldByName(f.Name)
+		if !sf.IsValid() {
+			continue
+		}
+		if err := c.field(dv.Field(i), sf); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return d, nil
+}
+
+// field sets the dst field d to the converted ast field v.
+func (c converter) field(d, v reflect.Value) error {
+	switch {
+	case v.Type() == posType:
+		if d.Kind() == reflect.Bool {
+			d.SetBool(v.Interface().(token.Pos).IsValid())
+		}
+		return nil
+	case v.Type() == d.Type():
+		d.Set(v)
+		return nil
+	case v.Type().ConvertibleTo(d.Type()) && v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface && v.Kind() != reflect.Slice:
+		// E.g. ChanDir.
+		d.Set(v.Convert(d.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if !v.Type().Implements(nodeType) {
+			return fmt.Errorf("unsupported field type %s", v.Type())
+		}
+		n, err := c.node(v)
+		if err != nil {
+			return err
+		}
+		d.Set(n)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		s := reflect.MakeSlice(d.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := c.field(s.Index(i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+		d.Set(s)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package gorder
+
+import (
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// Sorter decides the order of the top-level declarations in a file. The
+// directives, method orders, lifecycle pairs and size tiebreakers are
+// applied on top of it.
+type Sorter interface {
+	// Ranker returns the Ranker for the declarations decls of a file.
+	Ranker(decls []dst.Decl, opts Options) Ranker
+}
+
+// Ranker ranks the top-level declarations of a file.
+type Ranker interface {
+	// Key returns the sort name and section weight of d. Lower weights sort
+	// higher up. Declarations with weight -1 have no opinionated position
+	// and keep their order relative to each other. Methods and types must
+	// be named on the form Receiver.Name to be kept together.
+	Key(d dst.Decl) (string, int)
+
+	// Less reports whether the sort name a sorts before b in the same
+	// section.
+	Less(a, b string) bool
+}
+
+// The built-in sorters.
+var (
+	// HeuristicSorter sorts declarations into the sections given by
+	// Options.Weights, e.g. main, exported functions, constructors, types
+	// with their methods and other functions.
+	HeuristicSorter Sorter = heuristicSorter{}
+
+	// AlphaSorter ignores the sections and sorts functions and types by
+	// name, keeping methods below their type.
+	AlphaSorter Sorter = alphaSorter{}
+
+	// GodocSorter sorts like the go doc output: functions, then types, each
+	// followed by the functions returning it and its methods.
+	GodocSorter Sorter = godocSorter{}
+)
+
+// sorter returns the Sorter to use with o.
+func (o Options) sorter() Sorter {
+	switch {
+	case o.Sorter != nil:
+		return o.Sorter
+	case o.Mode == ModeAlpha:
+		return AlphaSorter
+	case o.Mode == ModeGodoc:
+		return GodocSorter
+	default:
+		return HeuristicSorter
+	}
+}
+
+type heuristicSorter struct{}
+
+func (heuristicSorter) Ranker(decls []dst.Decl, opts Options) Ranker {
+	return newDeclRanker(decls, opts)
+}
+
+type alphaSorter struct{}
+
+func (alphaSorter) Ranker(decls []dst.Decl, opts Options) Ranker {
+	return alphaRanker{newDeclRanker(decls, opts)}
+}
+
+// alphaRanker puts all declarations with a position in the same section.
+type alphaRanker struct {
+	*declRanker
+}
+
+func (r alphaRanker) Key(d dst.Decl) (string, int) {
+	s, weight := r.declRanker.Key(d)
+	if weight == -1 {
+		return s, weight
+	}
+	return s, 0
+}
+
+func (r alphaRanker) Less(a, b string) bool {
+	if na, nb := alphaName(a), alphaName(b); na != nb {
+		return na < nb
+	}
+	return r.declRanker.Less(a, b)
+}
+
+// alphaName returns the name s is sorted by in modeAlpha: its receiver, or
+// the name itself for plain functions.
+func alphaName(s string) string {
+	if i := strings.Index(s, "."); i != -1 {
+		s = s[:i]
+	}
+	return strings.ToLower(s)
+}
+
+type godocSorter struct{}
+
+func (godocSorter) Ranker(decls []dst.Decl, opts Options) Ranker {
+	opts.CtorReturn = true
+	return godocRanker{newDeclRanker(decls, opts)}
+}
+
+// godocMarker is put between the receiver and name of a function listed
+// under a type.
+const godocMarker = "_____"
+
+// godocRanker puts functions returning a type declared in the file in the
+// type's section, right below the type.
+type godocRanker struct {
+	*declRanker
+}
+
+func (r godocRanker) Key(d dst.Decl) (string, int) {
+	if f, ok := d.(*dst.FuncDecl); ok && f.Recv == nil && f.Name.Name != "main" {
+		if t := r.returnedFileType(f); t != "" {
+			return t + "." + godocMarker + f.Name.Name, r.opts.Weights.Type
+		}
+	}
+	return r.declRanker.Key(d)
+}
+
+func (r godocRanker) Less(a, b string) bool {
+	ra, na := splitOnDot(a)
+	rb, nb := splitOnDot(b)
+	if ra != rb || ra == "" {
+		return r.declRanker.Less(a, b)
+	}
+
+	// The type, then its functions, then its methods.
+	rank := func(name string) int {
+		switch {
+		case name == magicTypeMarker:
+			return 0
+		case strings.HasPrefix(name, godocMarker):
+			return 1
+		default:
+			return 2
+		}
+	}
+	ka, kb := rank(na), rank(nb)
+	if ka != kb {
+		return ka < kb
+	}
+	if ka == 1 {
+		return na < nb
+	}
+	return r.declRanker.Less(a, b)
+}
@@ -0,0 +1,39 @@
+package gorder
+
+import (
+	"github.com/dave/dst"
+)
+
+// normalizeSpacing separates decls by exactly one blank line and removes
+// the empty line decorations left dangling around comments by moves.
+func normalizeSpacing(decls []dst.Decl) {
+	for i, d := range decls {
+		decs := d.Decorations()
+		decs.Before = dst.EmptyLine
+		decs.After = dst.EmptyLine
+		if i == len(decls)-1 {
+			decs.After = dst.NewLine
+		}
+
+		// Newlines before the first comment only add to the spacing above.
+		start := decs.Start
+		for len(start) > 0 && start[0] == "\n" {
+			start = start[1:]
+		}
+		decs.Start.Replace(collapseNewlines(start)...)
+		decs.End.Replace(collapseNewlines(decs.End)...)
+	}
+}
+
+// collapseNewlines returns decs with runs of newline decorations cut to
+// one, which the printer renders as a single blank line.
+func collapseNewlines(decs dst.Decorations) []string {
+	var result []string
+	for i, d := range decs {
+		if d == "\n" && i > 0 && decs[i-1] == "\n" {
+			continue
+		}
+		result = append(result, d)
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package gorder
+
+import (
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// Placement of function stubs, declared without a body and implemented in
+// assembly, and of declarations with a //go:linkname directive.
+const (
+	// StubsKeep keeps them below the declaration they follow in the file.
+	StubsKeep = "keep"
+
+	// StubsGroup moves them, in sorted order, to the end of the file.
+	StubsGroup = "group"
+
+	// StubsSort sorts them as other declarations.
+	StubsSort = "sort"
+)
+
+// isStub reports whether d is a function without a body or a declaration
+// with a //go:linkname directive.
+func isStub(d dst.Decl) bool {
+	if f, ok := d.(*dst.FuncDecl); ok && f.Body == nil {
+		return true
+	}
+	for _, c := range d.Decorations().Start {
+		if strings.HasPrefix(c, "//go:linkname ") {
+			return true
+		}
+	}
+	return false
+}
+
+// placeStubs applies the policy to the stubs in the sorted decls. With
+// StubsKeep, the stubs are left out while sorting instead; see detachStubs.
+func placeStubs(sorted []dst.Decl, policy string) {
+	if policy != StubsGroup {
+		return
+	}
+	var stubs, rest []dst.Decl
+	for _, d := range sorted {
+		if isStub(d) {
+			stubs = append(stubs, d)
+		} else {
+			rest = append(rest, d)
+		}
+	}
+	copy(sorted[copy(sorted, rest):], stubs)
+}
+
+// detachStubs returns decls without the stubs, and the stubs by the
+// declaration they follow, nil for those before any other, to put back with
+// attachStubs once the rest is sorted. The stubs stay next to their
+// neighbour, whatever moves around them, and sorting again leaves them be.
+func detachStubs(decls []dst.Decl) ([]dst.Decl, map[dst.Decl][]dst.Decl) {
+	var (
+		rest  []dst.Decl
+		stubs map[dst.Decl][]dst.Decl
+		prev  dst.Decl
+	)
+	for _, d := range decls {
+		if !isStub(d) {
+			rest = append(rest, d)
+			prev = d
+			continue
+		}
+		if stubs == nil {
+			stubs = make(map[dst.Decl][]dst.Decl)
+		}
+		stubs[prev] = append(stubs[prev], d)
+	}
+	if stubs == nil {
+		return decls, nil
+	}
+	return rest, stubs
+}
+
+// attachStubs returns the sorted decls with the stubs detached by
+// detachStubs put back after the declarations they followed.
+func attachStubs(sorted []dst.Decl, stubs map[dst.Decl][]dst.Decl) []dst.Decl {
+	result := append([]dst.Decl(nil), stubs[nil]...)
+	for _, d := range sorted {
+		result = append(result, d)
+		result = append(result, stubs[d]...)
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+package gorder
+
+import "testing"
+
+func TestStubs(t *testing.T) {
+	const src = `package p
+
+import _ "unsafe"
+
+func Zeta() {}
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+func Alpha() {}
+
+type Clock struct{}
+
+func stub()
+
+func Beta() {}
+`
+	for _, test := range []struct {
+		name string
+		opts func(o *Options)
+		want string
+	}{
+		{
+			"keep",
+			func(o *Options) {},
+			`package p
+
+import _ "unsafe"
+
+func Alpha() {}
+
+func Beta() {}
+
+func Zeta() {}
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+type Clock struct{}
+
+func stub()
+`,
+		},
+		{
+			"keep with other kinds pinned",
+			func(o *Options) { o.Kinds = KindFunc },
+			`package p
+
+import _ "unsafe"
+
+func Alpha() {}
+
+func Beta() {}
+
+type Clock struct{}
+
+func stub()
+
+func Zeta() {}
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+`,
+		},
+		{
+			"keep with the caller mode",
+			func(o *Options) { o.Mode = ModeCaller },
+			`package p
+
+import _ "unsafe"
+
+func Alpha() {}
+
+func Beta() {}
+
+func Zeta() {}
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+type Clock struct{}
+
+func stub()
+`,
+		},
+		{
+			"group",
+			func(o *Options) { o.Stubs = StubsGroup },
+			`package p
+
+import _ "unsafe"
+
+func Alpha() {}
+
+func Beta() {}
+
+func Zeta() {}
+
+type Clock struct{}
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+func stub()
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			test.opts(&opts)
+			got := sortSource(t, src, opts)
+			if got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+			if again := sortSource(t, got, opts); again != got {
+				t.Errorf("sorting again changes it:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestStubsKeepCaller(t *testing.T) {
+	// The stubs follow the declarations they were below as these move, e.g.
+	// helpers below their callers.
+	src := `package p
+
+func helper() int {
+	return 1
+}
+
+func stub()
+
+func other()
+
+func Zed() {}
+
+func Alpha() {
+	helper()
+}
+
+func asm() int
+`
+	want := `package p
+
+func Alpha() {
+	helper()
+}
+
+func asm() int
+
+func helper() int {
+	return 1
+}
+
+func stub()
+
+func other()
+
+func Zed() {}
+`
+	opts := DefaultOptions()
+	opts.Mode = ModeCaller
+	got := sortSource(t, src, opts)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if again := sortSource(t, got, opts); again != got {
+		t.Errorf("sorting again changes it:\n%s", again)
+	}
+}
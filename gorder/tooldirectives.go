@@ -0,0 +1,58 @@
+package gorder
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// toolDirectiveRe matches the directives read by the compiler and other
+// tools, e.g. //go:embed or //nolint:errcheck; see go/ast.
+var toolDirectiveRe = regexp.MustCompile(`^//(line |export |extern |[a-z0-9]+:[a-z0-9])`)
+
+// isToolDirective reports whether the comment c is a compiler or tool
+// directive. The gorder directives are not.
+func isToolDirective(c string) bool {
+	return toolDirectiveRe.MatchString(c) && !strings.HasPrefix(c, DirectivePrefix)
+}
+
+// detachToolDirectives keeps comment blocks holding only tool directives,
+// e.g. //go:generate, in place when they are separated by a blank line from
+// the first declaration after the package clause and imports, as they apply
+// to the file and not the declaration.
+func detachToolDirectives(file *dst.File) {
+	i := 0
+	for i < len(file.Decls) && preserveOrder(file.Decls[i]) {
+		i++
+	}
+	if i == len(file.Decls) {
+		return
+	}
+
+	start := file.Decls[i].Decorations().Start
+	end := 0
+	for j := 0; j < len(start); j++ {
+		if start[j] != "\n" {
+			if !isToolDirective(start[j]) {
+				break
+			}
+			continue
+		}
+		end = j + 1
+	}
+	if end == 0 {
+		return
+	}
+
+	block := append([]string(nil), start[:end]...)
+	file.Decls[i].Decorations().Start.Replace(start[end:]...)
+	if i == 0 {
+		file.Decs.Name.Append("\n")
+		file.Decs.Name.Append(block...)
+		return
+	}
+	prev := file.Decls[i-1].Decorations()
+	prev.End.Append("\n")
+	prev.End.Append(block...)
+}
@@ -0,0 +1,126 @@
+package gorder
+
+import "testing"
+
+func TestToolDirectives(t *testing.T) {
+	for _, test := range []struct {
+		name, src, want string
+	}{
+		{
+			"moving with their declaration",
+			`package p
+
+//go:noinline
+func zed() int { return 1 } //nolint:unused
+
+// Alpha has a doc comment above its directive.
+//
+//go:noinline
+func Alpha() int { return zed() }
+`,
+			`package p
+
+// Alpha has a doc comment above its directive.
+//
+//go:noinline
+func Alpha() int { return zed() }
+
+//go:noinline
+func zed() int { return 1 } //nolint:unused
+`,
+		},
+		{
+			"go:embed staying above its var",
+			`package p
+
+import "embed"
+
+func zed() {}
+
+//go:embed testdata
+var alpha embed.FS
+`,
+			`package p
+
+import "embed"
+
+//go:embed testdata
+var alpha embed.FS
+
+func zed() {}
+`,
+		},
+		{
+			"go:generate after the imports staying in place",
+			`package p
+
+import "fmt"
+
+//go:generate stringer -type=kind
+
+type kind int
+
+func Zed() { fmt.Println() }
+
+func Alpha() {}
+`,
+			`package p
+
+import "fmt"
+
+//go:generate stringer -type=kind
+
+func Alpha() {}
+
+func Zed() { fmt.Println() }
+
+type kind int
+`,
+		},
+		{
+			"go:generate after the package clause staying in place",
+			`package p
+
+//go:generate go run gen.go
+
+func zed() {}
+
+func alpha() {}
+`,
+			`package p
+
+//go:generate go run gen.go
+
+func alpha() {}
+
+func zed() {}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sortSource(t, test.src, DefaultOptions()); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsToolDirective(t *testing.T) {
+	for _, test := range []struct {
+		comment string
+		want    bool
+	}{
+		{"//go:generate stringer", true},
+		{"//go:embed testdata", true},
+		{"//nolint:errcheck", true},
+		{"//line foo.go:10", true},
+		{"//export Foo", true},
+		{"// go:generate with a space", false},
+		{"// A comment.", false},
+		{DirectivePrefix + "order a b", false},
+	} {
+		if got := isToolDirective(test.comment); got != test.want {
+			t.Errorf("%q: got %t, want %t", test.comment, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package gorder
+
+import (
+	"sort"
+
+	"github.com/dave/dst"
+)
+
+// alignDecls reorders the declarations with keys (see declKeys) in order
+// among the positions they occupy, so they follow order. Other
+// declarations stay where they are.
+func alignDecls(decls []dst.Decl, order []string) {
+	index := make(map[string]int, len(order))
+	for i, key := range order {
+		index[key] = i
+	}
+
+	keys := declKeys(decls)
+
+	var (
+		slots  []int
+		shared []dst.Decl
+	)
+	for i, d := range decls {
+		if _, ok := index[keys[d]]; ok {
+			slots = append(slots, i)
+			shared = append(shared, d)
+		}
+	}
+
+	sort.SliceStable(shared, func(i, j int) bool {
+		return index[keys[shared[i]]] < index[keys[shared[j]]]
+	})
+
+	for i, slot := range slots {
+		decls[slot] = shared[i]
+	}
+}
@@ -0,0 +1,283 @@
+package gorder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// errTokensChanged guards against sorting dropping, duplicating or changing
+// code, which would be a bug in gorder or the printer.
+var errTokensChanged = errors.New("internal error, the sorted declarations differ from the original in more than their order, leaving the file as is")
+
+// checkOutput returns an error if out, the sorted source, is not valid Go,
+// or if its declarations differ from those in src in more than their order
+// and that of the members sorted. The tokens are not compared with the
+// BeforeSort and AfterSort hooks set, which may change the code.
+func checkOutput(src, out []byte, opts Options) error {
+	if bytes.Equal(src, out) {
+		// Unchanged, and src parsed.
+		return nil
+	}
+
+	outFset := token.NewFileSet()
+	outFile, err := parser.ParseFile(outFset, "", out, parser.SkipObjectResolution)
+	if err != nil {
+		return fmt.Errorf("internal error, the sorted source does not parse, leaving the file as is: %s", err)
+	}
+
+	if opts.BeforeSort != nil || opts.AfterSort != nil {
+		return nil
+	}
+
+	srcFset := token.NewFileSet()
+	srcFile, err := parser.ParseFile(srcFset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		// Parsed by dst already.
+		return nil
+	}
+
+	if !maps.EqualFunc(declTokens(srcFset, srcFile, src, opts), declTokens(outFset, outFile, out, opts), slices.Equal[[]string]) {
+		return errTokensChanged
+	}
+	return nil
+}
+
+// declTokens returns the tokens, without comments and semicolons, of the
+// top-level declarations in f, parsed from src, keyed by their kind,
+// receiver and names. Several declarations with the same key, e.g. init
+// functions, are in sorted order. The members sorted with opts, the fields
+// and methods of struct and interface types and the elements of composite
+// literals, are compared in sorted order too, the rest of a declaration in
+// order. With opts.Imports, the imports, which may be merged and
+// deduplicated, are compared as a set instead.
+func declTokens(fset *token.FileSet, f *ast.File, src []byte, opts Options) map[string][]string {
+	tf := fset.File(f.Pos())
+	tokens := scanTokens(src)
+	decls := map[string][]string{"package": {f.Name.Name}}
+	var specs []string
+	for _, d := range f.Decls {
+		if g, ok := d.(*ast.GenDecl); ok && g.Tok == token.IMPORT && opts.Imports {
+			for _, spec := range g.Specs {
+				s := spec.(*ast.ImportSpec)
+				key := s.Path.Value
+				if s.Name != nil {
+					key = s.Name.Name + " " + key
+				}
+				specs = append(specs, key)
+			}
+			continue
+		}
+		key := verifyKey(d)
+		lists := sortedLists(tf, d, opts)
+		decls[key] = append(decls[key], tokenSeq(tokens, lists, tf.Offset(d.Pos()), tf.Offset(d.End())))
+	}
+	for _, ds := range decls {
+		slices.Sort(ds)
+	}
+	if specs != nil {
+		slices.Sort(specs)
+		decls["import"] = slices.Compact(specs)
+	}
+	return decls
+}
+
+// A srcToken is a token in a file, with its offset.
+type srcToken struct {
+	offset int
+	text   string
+}
+
+// scanTokens returns the tokens of src but the comments and semicolons.
+func scanTokens(src []byte) []srcToken {
+	var s scanner.Scanner
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	s.Init(file, src, nil, 0)
+
+	var tokens []srcToken
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON {
+			continue
+		}
+		// The literal tells the identifiers, keywords and literals apart,
+		// the operators have none.
+		if lit == "" {
+			lit = tok.String()
+		}
+		tokens = append(tokens, srcToken{offset: file.Offset(pos), text: lit})
+	}
+	return tokens
+}
+
+// sortedLists returns the lists of members in d that opts may sort, keyed
+// by their offset in tf, as the offsets of their members.
+func sortedLists(tf *token.File, d ast.Decl, opts Options) map[int][][2]int {
+	lists := make(map[int][][2]int)
+	add := func(nodes []ast.Node) {
+		if len(nodes) < 2 {
+			return
+		}
+		var list [][2]int
+		for _, n := range nodes {
+			list = append(list, [2]int{tf.Offset(n.Pos()), tf.Offset(n.End())})
+		}
+		lists[list[0][0]] = list
+	}
+	ast.Inspect(d, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.StructType:
+			if opts.StructFields && opts.Kinds.has(KindStruct) {
+				add(fieldNodes(v.Fields))
+			}
+		case *ast.InterfaceType:
+			if opts.Kinds.has(KindInterface) {
+				add(fieldNodes(v.Methods))
+			}
+		case *ast.CompositeLit:
+			if opts.StructLiterals || opts.MapLiterals {
+				var elts []ast.Node
+				for _, e := range v.Elts {
+					elts = append(elts, e)
+				}
+				add(elts)
+			}
+		}
+		return true
+	})
+	return lists
+}
+
+func fieldNodes(fields *ast.FieldList) []ast.Node {
+	if fields == nil {
+		return nil
+	}
+	var nodes []ast.Node
+	for _, f := range fields.List {
+		nodes = append(nodes, f)
+	}
+	return nodes
+}
+
+// tokenSeq returns the tokens between the offsets start and end, one per
+// line, with the members of the lists in sorted order, each on a line;
+// see sortedLists.
+func tokenSeq(tokens []srcToken, lists map[int][][2]int, start, end int) string {
+	i, _ := slices.BinarySearchFunc(tokens, start, func(t srcToken, offset int) int {
+		return t.offset - offset
+	})
+	var seq []string
+	for i < len(tokens) && tokens[i].offset < end {
+		t := tokens[i]
+		list, ok := lists[t.offset]
+		if !ok || list[len(list)-1][1] > end {
+			seq = append(seq, t.text)
+			i++
+			continue
+		}
+		var members []string
+		for _, m := range list {
+			member := tokenSeq(tokens, lists, m[0], m[1])
+			members = append(members, strings.ReplaceAll(member, "\n", " "))
+		}
+		slices.Sort(members)
+		seq = append(seq, members...)
+		// Skip the members and the commas between them.
+		for i < len(tokens) && tokens[i].offset < list[len(list)-1][1] {
+			i++
+		}
+	}
+	return strings.Join(seq, "\n")
+}
+
+// verifyKey returns the key of d in declTokens: its kind, receiver type and
+// names.
+func verifyKey(d ast.Decl) string {
+	var b strings.Builder
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		b.WriteString("func ")
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			b.WriteString(recvTypeName(d.Recv.List[0].Type) + ".")
+		}
+		b.WriteString(d.Name.Name)
+	case *ast.GenDecl:
+		b.WriteString(d.Tok.String())
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.ImportSpec:
+				b.WriteString(" " + s.Path.Value)
+			case *ast.TypeSpec:
+				b.WriteString(" " + s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					b.WriteString(" " + n.Name)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// recvTypeName returns the name of the receiver type e, without the pointer
+// and type parameters.
+func recvTypeName(e ast.Expr) string {
+	for {
+		switch t := e.(type) {
+		case *ast.StarExpr:
+			e = t.X
+		case *ast.IndexExpr:
+			e = t.X
+		case *ast.IndexListExpr:
+			e = t.X
+		case *ast.ParenExpr:
+			e = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// checkIdempotent sorts out, the sorted source, again with opts, without the
+// hooks, and returns an error if that changes it.
+func checkIdempotent(ctx context.Context, filename string, out []byte, opts Options) error {
+	opts.SelfCheck = false
+	opts.BeforeSort, opts.AfterSort = nil, nil
+	if opts.ChangedLines != nil {
+		// The lines are those of the source, not out; nothing moves again.
+		opts.ChangedLines = []LineRange{}
+	}
+	r, err := ReorderContext(ctx, filename, out, opts)
+	if err != nil {
+		return fmt.Errorf("self-check: %w", err)
+	}
+	if !bytes.Equal(r.Src, out) {
+		return fmt.Errorf("self-check: sorting again changes the file from line %d, leaving the file as is", firstDiffLine(out, r.Src))
+	}
+	return nil
+}
+
+// firstDiffLine returns the first line, counted from 1, where a and b
+// differ.
+func firstDiffLine(a, b []byte) int {
+	line := 1
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '\n' {
+			line++
+		}
+	}
+	return line
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// hookMarker marks the pre-commit hooks written by gorder hook install.
+const hookMarker = "# Installed by gorder hook install."
+
+// hookScript is the pre-commit hook written by gorder hook install.
+const hookScript = "#!/bin/sh\n" + hookMarker + "\nexec gorder hook run\n"
+
+// preCommitConfig is the hook entry for the pre-commit framework.
+const preCommitConfig = `- repo: local
+  hooks:
+    - id: gorder
+      name: gorder
+      entry: gorder hook run
+      language: system
+      types: [go]
+      pass_filenames: false
+`
+
+// runHook runs the hook subcommand given by args and returns the exit code.
+func runHook(r *configResolver, args []string) (int, error) {
+	if len(args) == 0 {
+		return exitUsage, errors.New("missing hook command, expected install or run")
+	}
+
+	switch args[0] {
+	case "install":
+		if err := hookInstall(args[1:]); err != nil {
+			return exitFailure, err
+		}
+		return exitClean, nil
+	case "run":
+		if len(args) > 1 {
+			return exitUsage, errors.New("usage: gorder hook run")
+		}
+		return hookRun(r)
+	default:
+		return exitUsage, fmt.Errorf("unknown hook command %q", args[0])
+	}
+}
+
+// hookInstall writes the git pre-commit hook running gorder hook run, or
+// with -pre-commit-config prints the entry for the pre-commit framework.
+func hookInstall(args []string) error {
+	fs := flag.NewFlagSet("hook install", flag.ContinueOnError)
+	force := fs.Bool("force", false, "replace a pre-commit hook not written by gorder")
+	framework := fs.Bool("pre-commit-config", false, "print the hook entry for .pre-commit-config.yaml instead")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: gorder hook install [-force] [-pre-commit-config]")
+	}
+
+	if *framework {
+		_, err := os.Stdout.WriteString(preCommitConfig)
+		return err
+	}
+
+	// This honors core.hooksPath.
+	out, err := git("", "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return err
+	}
+	dir := strings.TrimSpace(string(out))
+	filename := filepath.Join(dir, "pre-commit")
+
+	if b, err := os.ReadFile(filename); err == nil && !bytes.Contains(b, []byte(hookMarker)) && !*force {
+		return fmt.Errorf("%s exists, set -force to replace it", filename)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, []byte(hookScript), 0755); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file.
+	if err := os.Chmod(filename, 0755); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "wrote %s\n", filename)
+	return nil
+}
+
+// hookRun checks the staged Go files and prints the diffs of those out of
+// order. The staged content is checked, not the working tree, so partially
+// staged files are checked as they will be committed.
+func hookRun(r *configResolver) (int, error) {
+	out, err := git("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return exitFailure, err
+	}
+	top := strings.TrimSpace(string(out))
+
+	out, err = git(top, "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR", "--", "*.go")
+	if err != nil {
+		return exitFailure, err
+	}
+
+	colored, err := useColor(*color)
+	if err != nil {
+		return exitUsage, err
+	}
+
+	var reports []report
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name == "" {
+			continue
+		}
+		filename := filepath.Join(top, filepath.FromSlash(name))
+		c, err := r.resolve(filepath.Dir(filename))
+		if err != nil {
+			return exitUsage, err
+		}
+		excluded, err := c.excluded(filename)
+		if err != nil {
+			return exitUsage, err
+		}
+		if excluded {
+			continue
+		}
+
+		rep := report{File: filename}
+		if err := hookCheck(&rep, top, name, c, colorizer(colored)); err != nil {
+			rep.Error = newReportError(err)
+		}
+		os.Stdout.Write(rep.text)
+		reports = append(reports, rep)
+	}
+
+	printErrors(os.Stderr, reports)
+
+	code := exitCode(reports)
+	if code == exitChanged {
+		fmt.Fprintln(os.Stderr, "gorder: staged files are out of order; run gorder -w on them and stage the result, or commit with --no-verify to skip the check")
+	}
+	return code, nil
+}
+
+// hookCheck reorders the staged content of the file name, relative to the
+// repository root top, and records the result in rep.
+func hookCheck(rep *report, top, name string, c config, col colorizer) error {
+	src, err := git(top, "show", ":"+name)
+	if err != nil {
+		return err
+	}
+	if reason := skipReason(src, c); reason != "" {
+		rep.Skipped = reason
+		return nil
+	}
+
+	opts, err := fileOptions(rep.File, src, c)
+	if err != nil {
+		return err
+	}
+	res, err := gorder.Reorder(rep.File, src, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", rep.File, err)
+	}
+
+	rep.Moves = res.Moves
+	rep.line = firstChangedLine(src, res.Src)
+	rep.Changed = rep.line > 0
+	if rep.Changed {
+		oldMarks, newMarks := moveMarks(res.Moves)
+		rep.text = unifiedDiff("a/"+name, "b/"+name, src, res.Src, col, oldMarks, newMarks)
+	}
+	return nil
+}
+
+// git runs git with args in dir, the current directory if empty, and
+// returns its output.
+func git(dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// parseHunks returns the lines added or changed, per file, in the unified
+// diff read from r, e.g. from git diff, on the new side. Removed lines are
+// recorded as the empty range where they were; see gorder.LineRange. The
+// file names are those of the new side, with the b/ prefix of git diffs
+// removed. Deleted files are left out.
+func parseHunks(r io.Reader) (map[string][]gorder.LineRange, error) {
+	hunks := make(map[string][]gorder.LineRange)
+
+	var (
+		oldName  string
+		filename string
+		// The lines left in the current hunk, on the old and new side.
+		oldLeft, newLeft int
+		// The next line on the new side.
+		line int
+	)
+
+	add := func(lr gorder.LineRange) {
+		if filename == "" {
+			// A deleted file.
+			return
+		}
+		ranges := hunks[filename]
+		if n := len(ranges); n > 0 {
+			last := &ranges[n-1]
+			switch {
+			case lr.Start > lr.End && *last == lr:
+				// More removed lines at the same place.
+				return
+			case lr.Start <= lr.End && last.Start <= last.End && last.End == lr.Start-1:
+				last.End = lr.End
+				return
+			}
+		}
+		hunks[filename] = append(ranges, lr)
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		s := sc.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(s, "+"):
+				add(gorder.LineRange{Start: line, End: line})
+				line++
+				newLeft--
+			case strings.HasPrefix(s, "-"):
+				add(gorder.LineRange{Start: line, End: line - 1})
+				oldLeft--
+			case strings.HasPrefix(s, `\`):
+				// No newline at end of file.
+			default:
+				line++
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(s, "--- "):
+			oldName = diffName(s[4:])
+		case strings.HasPrefix(s, "+++ "):
+			filename = diffName(s[4:])
+			if filename == "/dev/null" {
+				filename = ""
+				break
+			}
+			if strings.HasPrefix(oldName, "a/") || oldName == "/dev/null" {
+				filename = strings.TrimPrefix(filename, "b/")
+			}
+			filename = filepath.FromSlash(filename)
+			if _, ok := hunks[filename]; !ok {
+				hunks[filename] = []gorder.LineRange{}
+			}
+		case strings.HasPrefix(s, "@@ "):
+			var err error
+			if line, oldLeft, newLeft, err = parseHunkHeader(s); err != nil {
+				return nil, fmt.Errorf("diff line %d: %s", n, err)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return hunks, nil
+}
+
+// diffName returns the file name in the --- or +++ line of a diff, without
+// the timestamp some tools add after a tab.
+func diffName(s string) string {
+	name, _, _ := strings.Cut(s, "\t")
+	return name
+}
+
+// parseHunkHeader parses the hunk header s, on the form
+// "@@ -start,count +start,count @@", and returns the first line and the
+// line counts on each side.
+func parseHunkHeader(s string) (line, oldCount, newCount int, err error) {
+	fields := strings.Fields(s)
+	if len(fields) < 4 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", s)
+	}
+	_, oldCount, err = parseHunkRange(fields[1][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", s)
+	}
+	line, newCount, err = parseHunkRange(fields[2][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", s)
+	}
+	if newCount == 0 {
+		// The empty range is after the start line.
+		line++
+	}
+	return line, oldCount, newCount, nil
+}
+
+// parseHunkRange parses "start,count" or "start", with a count of 1.
+func parseHunkRange(s string) (start, count int, err error) {
+	startStr, countStr, found := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if found {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// diffFiles returns the Go files in hunks, sorted.
+func diffFiles(hunks map[string][]gorder.LineRange) []string {
+	var filenames []string
+	for filename := range hunks {
+		if strings.HasSuffix(filename, ".go") {
+			filenames = append(filenames, filename)
+		}
+	}
+	slices.Sort(filenames)
+	return filenames
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// lintFinding is a violation reported by gorder lint, with the ID of the
+// rule it violates, one of rules or orderRule.
+type lintFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// runLint runs the lint subcommand, which prints the violations in the
+// files matching the patterns in args, ./... by default, without rewriting
+// them. It returns exitChanged if there are violations.
+func runLint(r *configResolver, args []string) (int, error) {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "print the findings as a JSON array")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage, err
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	var (
+		findings []lintFinding
+		failed   bool
+	)
+	for _, pattern := range patterns {
+		filenames, err := expandPattern(pattern)
+		if err != nil {
+			return exitUsage, err
+		}
+		for _, filename := range filenames {
+			c, err := r.resolve(filepath.Dir(filename))
+			if err != nil {
+				return exitUsage, err
+			}
+			if excluded, err := c.excluded(filename); err != nil {
+				return exitUsage, err
+			} else if excluded {
+				continue
+			}
+			res, src, err := handleFile(context.Background(), filename, false, false, c, nil)
+			if err == nil {
+				var found []lintFinding
+				found, err = lintFindings(filename, src, res)
+				findings = append(findings, found...)
+			}
+			var skip skipError
+			if err != nil && !errors.As(err, &skip) {
+				log.Print(err)
+				failed = true
+			}
+		}
+	}
+
+	if *jsonOut {
+		if findings == nil {
+			findings = []lintFinding{}
+		}
+		if err := writeJSON(os.Stdout, findings); err != nil {
+			return exitFailure, err
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Printf("%s:%d:%d: %s (%s)\n", f.File, f.Line, f.Column, f.Message, f.Rule)
+		}
+	}
+
+	switch {
+	case failed:
+		return exitFailure, nil
+	case len(findings) > 0:
+		return exitChanged, nil
+	}
+	return exitClean, nil
+}
+
+// lintFindings returns the violations in src, found by reordering it to
+// res: one per declaration moved, naming the declaration it should follow,
+// or one for the first line changed if none moved.
+func lintFindings(filename string, src []byte, res gorder.Result) ([]lintFinding, error) {
+	line := firstChangedLine(src, res.Src)
+	if line == 0 {
+		return nil, nil
+	}
+	if len(res.Moves) == 0 {
+		return []lintFinding{{File: filename, Line: line, Column: 1, Rule: orderRule, Message: "declarations are out of order"}}, nil
+	}
+
+	oldFset, newFset := token.NewFileSet(), token.NewFileSet()
+	oldFile, err := parser.ParseFile(oldFset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	newFile, err := parser.ParseFile(newFset, filename, res.Src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	oldDecls := make(map[string]ast.Decl)
+	for _, d := range oldFile.Decls {
+		if key := mappingKey(d); oldDecls[key] == nil {
+			oldDecls[key] = d
+		}
+	}
+
+	var findings []lintFinding
+	for _, m := range res.Moves {
+		if m.OldIndex >= len(oldFile.Decls) || m.NewIndex >= len(newFile.Decls) {
+			continue
+		}
+		pos := oldFset.Position(oldFile.Decls[m.OldIndex].Pos())
+		name := m.Name
+		if m.Receiver != "" {
+			name = m.Receiver + "." + name
+		}
+
+		where := "should appear first"
+		if m.NewIndex > 0 {
+			prev := newFile.Decls[m.NewIndex-1]
+			kind, prevName := declLabel(prev)
+			switch old := oldDecls[mappingKey(prev)]; {
+			case kind == "import":
+				where = "should appear right after the imports"
+			case old != nil:
+				if kind != m.Kind {
+					prevName = kind + " " + prevName
+				}
+				where = fmt.Sprintf("should appear after %s (line %d)", prevName, oldFset.Position(old.Pos()).Line)
+			}
+		}
+
+		findings = append(findings, lintFinding{
+			File:    filename,
+			Line:    pos.Line,
+			Column:  pos.Column,
+			Rule:    moveRule(m),
+			Message: fmt.Sprintf("%s %s (line %d) %s", m.Kind, name, pos.Line, where),
+		})
+	}
+	return findings, nil
+}
+
+// declLabel returns the kind and name of d as in gorder.Move, with the
+// receiver type of methods, e.g. method and Foo.Close.
+func declLabel(d ast.Decl) (string, string) {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return "method", receiverTypeName(d.Recv.List[0].Type) + "." + d.Name.Name
+		}
+		return "func", d.Name.Name
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.ImportSpec:
+				names = append(names, s.Path.Value)
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			}
+		}
+		return d.Tok.String(), strings.Join(names, ", ")
+	}
+	return "", ""
+}
+
+// receiverTypeName returns the name of the receiver type e, without the
+// pointer and type parameters.
+func receiverTypeName(e ast.Expr) string {
+	for {
+		switch t := e.(type) {
+		case *ast.StarExpr:
+			e = t.X
+		case *ast.IndexExpr:
+			e = t.X
+		case *ast.IndexListExpr:
+			e = t.X
+		case *ast.ParenExpr:
+			e = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// codeActionKind is the kind of the code action reordering a file.
+const codeActionKind = "source.organizeDeclarations"
+
+// runLSP runs the lsp subcommand, a language server on stdin and stdout
+// offering a code action, and with -formatting document formatting, that
+// reorders the open file.
+func runLSP(r *configResolver, args []string) error {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	formatting := fs.Bool("formatting", false, "also reorder on format requests, e.g. on save")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *cacheDir != "" {
+		var err error
+		if cache, err = newFileCache(*cacheDir); err != nil {
+			return err
+		}
+	}
+
+	s := &lspServer{
+		daemon:     &daemon{resolver: r, stamps: make(map[string]string)},
+		formatting: *formatting,
+		docs:       make(map[string]string),
+		in:         bufio.NewReader(os.Stdin),
+		out:        os.Stdout,
+	}
+	return s.run(context.Background())
+}
+
+// lspServer is a minimal language server. The documents are synced in
+// full.
+type lspServer struct {
+	daemon     *daemon
+	formatting bool
+
+	// The content of the open documents, by URI.
+	docs map[string]string
+
+	in  *bufio.Reader
+	out io.Writer
+
+	shutdown bool
+}
+
+// lspMessage is a JSON-RPC request, notification or response.
+type lspMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// The JSON-RPC error codes used.
+const (
+	lspParseError     = -32700
+	lspInvalidRequest = -32600
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+	lspRequestFailed  = -32803
+)
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+// run serves the messages until exit or the end of the input.
+func (s *lspServer) run(ctx context.Context) error {
+	for {
+		body, err := s.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var m lspMessage
+		if err := json.Unmarshal(body, &m); err != nil {
+			if err := s.write(lspMessage{Error: &lspError{Code: lspParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if m.Method == "exit" {
+			return nil
+		}
+
+		result, rerr := s.handle(ctx, m)
+		if m.ID == nil {
+			// A notification, not answered.
+			continue
+		}
+		resp := lspMessage{ID: m.ID, Result: result, Error: rerr}
+		if rerr == nil && result == nil {
+			resp.Result = json.RawMessage("null")
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles the message m and returns the result, if m is a request.
+func (s *lspServer) handle(ctx context.Context, m lspMessage) (any, *lspError) {
+	if s.shutdown && m.ID != nil {
+		return nil, &lspError{Code: lspInvalidRequest, Message: "shut down"}
+	}
+
+	switch m.Method {
+	case "initialize":
+		capabilities := map[string]any{
+			"textDocumentSync":   1, // Full.
+			"codeActionProvider": map[string]any{"codeActionKinds": []string{codeActionKind}},
+		}
+		if s.formatting {
+			capabilities["documentFormattingProvider"] = true
+		}
+		return map[string]any{
+			"capabilities": capabilities,
+			"serverInfo":   map[string]string{"name": "gorder", "version": toolInfo().Version},
+		}, nil
+	case "initialized", "textDocument/didSave", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument lspDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   lspDocument   `json:"textDocument"`
+			ContentChanges []lspDocument `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument lspDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/codeAction":
+		var p struct {
+			TextDocument lspDocument `json:"textDocument"`
+			Context      struct {
+				Only []string `json:"only"`
+			} `json:"context"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		if !wantsCodeAction(p.Context.Only) {
+			return []any{}, nil
+		}
+		edits, err := s.edits(ctx, p.TextDocument.URI)
+		if err != nil || len(edits) == 0 {
+			// Nothing to offer for files in order or failing.
+			return []any{}, nil
+		}
+		return []any{map[string]any{
+			"title": "Organize declarations",
+			"kind":  codeActionKind,
+			"edit": map[string]any{
+				"changes": map[string][]lspTextEdit{p.TextDocument.URI: edits},
+			},
+		}}, nil
+	case "textDocument/formatting":
+		var p struct {
+			TextDocument lspDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		edits, err := s.edits(ctx, p.TextDocument.URI)
+		if err != nil {
+			return nil, &lspError{Code: lspRequestFailed, Message: err.Error()}
+		}
+		return edits, nil
+	}
+
+	if m.ID == nil || strings.HasPrefix(m.Method, "$/") {
+		return nil, nil
+	}
+	return nil, &lspError{Code: lspMethodNotFound, Message: "method not supported: " + m.Method}
+}
+
+// wantsCodeAction reports whether the code action kinds in only, all if
+// empty, include codeActionKind.
+func wantsCodeAction(only []string) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, kind := range only {
+		if kind == "source" || kind == codeActionKind {
+			return true
+		}
+	}
+	return false
+}
+
+// edits returns the edits reordering the open document uri.
+func (s *lspServer) edits(ctx context.Context, uri string) ([]lspTextEdit, error) {
+	text, ok := s.docs[uri]
+	if !ok {
+		return nil, fmt.Errorf("%s: not open", uri)
+	}
+	filename, err := uriFilename(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := s.daemon.handle(ctx, daemonRequest{File: filename, Src: text})
+	if resp.Error != nil {
+		return nil, errors.New(resp.Error.Message)
+	}
+	return textEdits([]byte(text), []byte(resp.Src)), nil
+}
+
+// textEdits returns the edits turning a into b, one per run of changed
+// lines.
+func textEdits(a, b []byte) []lspTextEdit {
+	lines := splitLines(a)
+	edits := []lspTextEdit{}
+	for _, h := range changedHunks(a, b) {
+		start := lspPosition{Line: h.Start - 1}
+		end := lspPosition{Line: h.Start - 1 + h.Len}
+		if end.Line == len(lines) && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+			// The end of the last line, as there is no line after it.
+			last := lines[len(lines)-1]
+			end = lspPosition{Line: len(lines) - 1, Character: len(utf16.Encode([]rune(last)))}
+			if h.Len == 0 {
+				start = end
+			}
+		}
+		edits = append(edits, lspTextEdit{Range: lspRange{Start: start, End: end}, NewText: h.Text})
+	}
+	return edits
+}
+
+// uriFilename returns the file name of the file URI uri.
+func uriFilename(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("%s: not a file", uri)
+	}
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		// A Windows drive letter, e.g. /c:/src.
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// read returns the body of the next message.
+func (s *lspServer) read() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *lspServer) write(m lspMessage) error {
+	m.JSONRPC = "2.0"
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
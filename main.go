@@ -1,389 +1,780 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
-	"go/token"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"sort"
+	"runtime"
+	"slices"
 	"strings"
-	"unicode"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/dave/dst"
-	"github.com/dave/dst/decorator"
+	"github.com/bep/gorder/gorder"
 )
 
 var (
-	write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	write         = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	configFile    = flag.String("config", "", "config file to use instead of the discovered "+configName+" files")
+	noConfig      = flag.Bool("no-config", false, "ignore all config files")
+	format        = flag.String("format", formatText, "output format, one of text, json, sarif, github, checkstyle, rdjson or explain; all but text report the changes instead of printing the source")
+	diff          = flag.Bool("d", false, "display diffs instead of the reordered source")
+	patchFile     = flag.String("patch", "", "write the changes as a patch to this file, for git apply, instead of the reordered source")
+	color         = flag.String("color", colorAuto, "colorize the -d diffs: auto, always or never")
+	quiet         = flag.Bool("q", false, "print nothing but errors; see the exit code for the result")
+	summary       = flag.Bool("summary", false, "print a summary of the run to stderr")
+	manifestName  = flag.String("manifest", "", "write a JSON manifest of the files read and written, with their hashes and config, to this file")
+	zipFile       = flag.String("zip", "", "read the files from this zip archive and write the archive with the results to stdout")
+	cacheDir      = flag.String("cache", "", "directory to cache the files known to be in order in, to skip them on later runs")
+	force         = flag.Bool("force", false, "with -w, write files above -max-moves-percent")
+	stream        = flag.Bool("stream", false, "print the errors, diffs and findings of each file as soon as it is done instead of at the end, holding little memory on large trees; with -format text, github or explain")
+	jobs          = flag.Int("jobs", runtime.GOMAXPROCS(0), "number of files to process in parallel")
+	cpuProfile    = flag.String("cpuprofile", "", "write a CPU profile to this file")
+	memProfile    = flag.String("memprofile", "", "write a memory profile to this file when done")
+	traceFile     = flag.String("trace", "", "write an execution trace to this file")
+	positionsFile = flag.String("positions", "", "write a JSON file mapping the old line ranges of the declarations that moved or shifted to the new ones, for editors to restore the cursor; also added to -format=json")
+	modules       = flag.Bool("modules", false, "report the results per module, from the go.mod of each file; with a pattern dir/... and a go.work in dir, process the files of the workspace modules")
+	overlayFile   = flag.String("overlay", "", "JSON file replacing the content of files, as for go build -overlay; the files replaced are reported and, with -w, the replacements written")
+	hunks         = flag.Bool("hunks", false, "read a unified diff, e.g. from git diff, from stdin and only move the declarations it adds or changes, in the files it changes")
+	stdio         = flag.Bool("stdio", false, "for hermetic build rules: reorder stdin to stdout with the settings of the flags alone, reading no config files, environment or other files")
 )
 
-const (
-	magicTypeMarker = "______"
-)
+// cfg holds the configuration; the flags write to it directly.
+var cfg = defaultConfig()
+
+// cache is the -cache, if set.
+var cache *fileCache
 
 func main() {
+	start := time.Now()
 	log.SetFlags(0)
 	log.SetPrefix("error: ")
 	flag.Usage = usage
+	registerFlags(flag.CommandLine, &cfg)
 	flag.Parse()
 
-	if flag.NArg() != 1 {
-		log.Fatal("missing filename")
+	if *stdio {
+		code, err := runStdio(flag.Args())
+		if err != nil {
+			fatal(code, err)
+		}
+		exit(code)
 	}
 
-	pattern := flag.Arg(0)
-	filenames, err := filepath.Glob(pattern)
-	if err != nil {
-		log.Fatal(err)
+	env := envFlags(flag.CommandLine)
+	// These are needed before any config is resolved.
+	for _, name := range []string{"w", "config", "no-config", "d", "patch", "color", "format", "q", "summary", "manifest", "zip", "cache", "force", "stream", "jobs", "cpuprofile", "memprofile", "trace", "hunks", "overlay", "modules", "positions"} {
+		if v, ok := env[name]; ok {
+			if err := flag.Set(name, v); err != nil {
+				fatalf(exitUsage, "%s: %s", envName(name), err)
+			}
+		}
 	}
 
-	w := *write
-
-	if len(filenames) > 1 && !w {
-		log.Fatal("multiple file matches require the -w flag")
+	if err := startProfiles(*cpuProfile, *memProfile, *traceFile); err != nil {
+		fatal(exitFailure, err)
 	}
 
-	if len(filenames) == 0 {
-		fmt.Fprintf(os.Stderr, "Pattern %q matched zero files\n", pattern)
-		return
-	}
+	resolver := newConfigResolver(flag.CommandLine, &cfg, env, *configFile, *noConfig)
 
-	for _, filename := range filenames {
-		if err := handleFile(filename, w); err != nil {
-			log.Fatal(err)
+	switch flag.Arg(0) {
+	case "config":
+		if err := runConfig(resolver, flag.Args()[1:]); err != nil {
+			fatal(exitUsage, err)
+		}
+		exit(exitClean)
+	case "report":
+		if err := runReport(resolver, flag.Args()[1:]); err != nil {
+			fatal(exitFailure, err)
+		}
+		exit(exitClean)
+	case "daemon":
+		if err := runDaemon(resolver, flag.Args()[1:]); err != nil {
+			fatal(exitFailure, err)
+		}
+		exit(exitClean)
+	case "serve":
+		if err := runServe(resolver, flag.Args()[1:]); err != nil {
+			fatal(exitFailure, err)
+		}
+		exit(exitClean)
+	case "mergetool":
+		code, err := runMergetool(resolver, flag.Args()[1:])
+		if err != nil {
+			fatal(code, err)
+		}
+		exit(code)
+	case "split":
+		code, err := runSplit(resolver, flag.Args()[1:])
+		if err != nil {
+			fatal(code, err)
+		}
+		exit(code)
+	case "merge":
+		code, err := runMerge(resolver, flag.Args()[1:])
+		if err != nil {
+			fatal(code, err)
+		}
+		exit(code)
+	case "relocate":
+		code, err := runRelocate(resolver, flag.Args()[1:])
+		if err != nil {
+			fatal(code, err)
+		}
+		exit(code)
+	case "lint":
+		code, err := runLint(resolver, flag.Args()[1:])
+		if err != nil {
+			fatal(code, err)
 		}
+		exit(code)
+	case "lsp":
+		if err := runLSP(resolver, flag.Args()[1:]); err != nil {
+			fatal(exitFailure, err)
+		}
+		exit(exitClean)
+	case "hook":
+		code, err := runHook(resolver, flag.Args()[1:])
+		if err != nil {
+			fatal(code, err)
+		}
+		exit(code)
 	}
-}
-
-func usage() {
-	fmt.Fprintf(os.Stderr, "usage: gorder [flags] [filename]\n")
-	flag.PrintDefaults()
-}
-
-func handleFile(filename string, write bool) error {
-	var perm os.FileMode = 0644
 
-	f, err := os.Open(filename)
-	if err != nil {
-		return err
+	pattern := flag.Arg(0)
+	switch {
+	case *hunks:
+		if flag.NArg() > 0 {
+			fatal(exitUsage, "the -hunks flag takes the files from the diff, not a pattern")
+		}
+		if *zipFile != "" {
+			fatal(exitUsage, "the -hunks flag cannot be used with -zip")
+		}
+	case flag.NArg() == 0 && goGenerate():
+		// The file with the //go:generate line, in the current directory.
+		pattern = os.Getenv("GOFILE")
+	case flag.NArg() != 1:
+		fatal(exitUsage, "missing filename")
 	}
 
-	fi, err := f.Stat()
-	if err != nil {
-		return err
+	switch *format {
+	case formatText, formatJSON, formatSARIF, formatGitHub, formatCheckstyle, formatRDJSON, formatExplain:
+	default:
+		fatalf(exitUsage, "invalid -format value %q", *format)
 	}
 
-	perm = fi.Mode().Perm()
-
-	src, err := ioutil.ReadAll(f)
-	if err != nil {
-		return err
+	if *zipFile != "" {
+		if *write {
+			fatal(exitUsage, "the -w flag cannot be used with -zip")
+		}
+		if err := handleZip(resolver, *zipFile, pattern); err != nil {
+			fatal(exitFailure, err)
+		}
+		exit(exitClean)
 	}
 
-	f.Close()
-
-	file, err := decorator.Parse(src)
+	var (
+		filenames []string
+		changed   map[string][]gorder.LineRange
+		err       error
+	)
+	if *hunks {
+		if changed, err = parseHunks(os.Stdin); err == nil {
+			filenames = diffFiles(changed)
+		}
+	} else {
+		filenames, err = expandPattern(pattern)
+	}
 	if err != nil {
-		return err
+		fatal(exitUsage, err)
 	}
 
-	dst.Inspect(file, func(n dst.Node) bool {
-		switch v := n.(type) {
-		case *dst.File:
-			sortDecls(v.Decls)
-		case *dst.InterfaceType:
-			sortFieldList(v.Methods)
-		case *dst.StructType:
-		case *dst.FieldList:
-		case nil:
-		default:
-
+	mods := newModuleResolver()
+	if root, ok := patternRoot(pattern); ok && *modules {
+		if files, ok, err := workspaceFiles(mods, filepath.FromSlash(root)); err != nil {
+			fatal(exitUsage, err)
+		} else if ok {
+			filenames = files
 		}
+	}
 
-		return true
+	if *overlayFile != "" {
+		if fileOverlay, err = readOverlay(*overlayFile); err != nil {
+			fatal(exitUsage, err)
+		}
+		if filenames, err = fileOverlay.expand(pattern, filenames); err != nil {
+			fatal(exitUsage, err)
+		}
+	}
 
-	})
+	if *cacheDir != "" {
+		if cache, err = newFileCache(*cacheDir); err != nil {
+			fatal(exitFailure, err)
+		}
+	}
 
-	var out io.Writer
+	w := *write
 
-	if write {
-		f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+	// The files to process, with the config resolved for each.
+	var (
+		files    []fileJob
+		excluded int
+		// The files excluded per module, with -modules.
+		moduleExcluded = make(map[string]int)
+	)
+	for _, filename := range filenames {
+		c, err := resolver.resolve(filepath.Dir(filename))
 		if err != nil {
-			return err
+			fatal(exitUsage, err)
 		}
-		defer f.Close()
-		out = f
-	} else {
-		out = os.Stdout
+		var m goModule
+		if *modules {
+			if m, err = mods.module(filepath.Dir(filename)); err != nil {
+				fatal(exitUsage, err)
+			}
+		}
+		skip, err := c.excluded(filename)
+		if err != nil {
+			fatal(exitUsage, err)
+		}
+		if skip {
+			excluded++
+			moduleExcluded[m.Path]++
+			continue
+		}
+		if _, err := c.options(); err != nil {
+			fatal(exitUsage, err)
+		}
+		if *force {
+			c.MaxMovesPercent = 0
+		}
+		c.changed = changed[filename]
+		files = append(files, fileJob{filename: filename, cfg: c, module: m.Path})
 	}
-
-	if err := decorator.Fprint(out, file); err != nil {
-		log.Fatal(err)
+	if *modules {
+		slices.SortStableFunc(files, func(a, b fileJob) int {
+			return strings.Compare(a.module, b.module)
+		})
 	}
 
-	return nil
-}
+	if *diff && *format != formatText {
+		fatal(exitUsage, "the -d flag cannot be used with -format")
+	}
 
-func sortFieldList(fields *dst.FieldList) {
-	sort.SliceStable(fields.List, func(i, j int) bool {
-		fi, fj := fields.List[i], fields.List[j]
-		ni, nj := len(fi.Names), len(fj.Names)
-		if ni == 0 && nj == 0 {
-			return less(fi.Type, fj.Type)
-		}
+	if *patchFile != "" && (w || *diff || *format != formatText) {
+		fatal(exitUsage, "the -patch flag cannot be used with -w, -d or -format")
+	}
 
-		if ni == 0 {
-			return true
+	if *stream {
+		switch {
+		case *patchFile != "":
+			fatal(exitUsage, "the -stream flag cannot be used with -patch")
+		case *format != formatText && *format != formatGitHub && *format != formatExplain:
+			fatalf(exitUsage, "the -stream flag cannot be used with -format=%s", *format)
 		}
+	}
 
-		if nj == 0 {
-			return false
-		}
+	colored, err := useColor(*color)
+	if err != nil {
+		fatal(exitUsage, err)
+	}
+	out := output{write: w, format: *format, diff: *diff, patch: *patchFile != "", manifest: *manifestName != "", positions: *positionsFile != "", quiet: *quiet, color: colorizer(colored)}
 
-		ll := lessStringers(fi.Names[0], fj.Names[0])
+	if len(files) > 1 && !w && !out.diff && !out.patch && !out.quiet && *format == formatText {
+		fatal(exitUsage, "multiple file matches require the -w flag")
+	}
 
-		return ll
-	})
-}
+	if len(filenames) == 0 {
+		switch {
+		case out.quiet:
+		case *hunks:
+			fmt.Fprintf(os.Stderr, "The diff changes no Go files\n")
+		default:
+			fmt.Fprintf(os.Stderr, "Pattern %q matched zero files\n", pattern)
+		}
+		exit(exitClean)
+	}
 
-func sortDecls(decls []dst.Decl) {
-	sort.SliceStable(decls, func(i, j int) bool {
-		di, dj := decls[i], decls[j]
+	// On interrupt, the files being processed are finished and the rest are
+	// left untouched.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		const (
-			// Less means higher up. We do some adjustments between these,
-			// so keep some empty space.
-			funcWeight            = 200
-			typeWeight            = 100
-			constructorFuncWeight = 50 // newSomething
-			exportedFuncWeight    = 30
-			mainFuncWeight        = 10
-		)
+	var emit func(report)
+	if *stream {
+		emit = func(r report) { streamReport(r, out) }
+	}
 
-		if preserveOrder(di) || preserveOrder(dj) {
-			return i < j
+	reports, err := handleFiles(ctx, files, out, *jobs, emit)
+	stop()
+	if *format == formatText && !*stream {
+		printErrors(os.Stderr, reports)
+	}
+	if !out.quiet && !*stream {
+		if *format != formatText {
+			if err := printReports(os.Stdout, *format, reports); err != nil {
+				fatal(exitFailure, err)
+			}
 		}
-
-		funcName := func(d dst.Decl) (string, int) {
-			f, ok := d.(*dst.FuncDecl)
-			if !ok {
-				return "", -1
+		if out.diff {
+			for _, r := range reports {
+				os.Stdout.Write(r.text)
 			}
+		}
+	}
+	if out.positions {
+		if err := writePositions(*positionsFile, reports); err != nil {
+			fatal(exitFailure, err)
+		}
+	}
+	if out.manifest {
+		if err := writeManifest(*manifestName, files, reports, w); err != nil {
+			fatal(exitFailure, err)
+		}
+	}
+	if out.patch {
+		var patch []byte
+		for _, r := range reports {
+			patch = append(patch, r.text...)
+		}
+		if err := os.WriteFile(*patchFile, patch, 0644); err != nil {
+			fatal(exitFailure, err)
+		}
+	}
+	if !out.quiet {
+		if *summary {
+			if *modules {
+				printModuleSummaries(os.Stderr, moduleExcluded, reports, time.Since(start))
+			} else {
+				printSummary(os.Stderr, len(filenames), excluded, reports, time.Since(start))
+			}
+		}
+	}
+	if err != nil {
+		fatal(exitFailure, err)
+	}
+	code := exitCode(reports)
+	if code == exitChanged && w && goGenerate() {
+		// go generate stops at the first command failing.
+		code = exitClean
+	}
+	exit(code)
+}
 
-			fr := fieldListName(f.Recv)
-
-			name := f.Name.String()
+// The exit codes.
+const (
+	exitClean   = 0 // No file needed reordering.
+	exitChanged = 1 // Some files were, or with -d or -q need to be, reordered.
+	exitUsage   = 2 // Invalid flags, arguments or config.
+	exitFailure = 3 // Some files could not be read, parsed or written.
+)
 
-			if fr == "" {
-				if name == "main" {
-					return name, mainFuncWeight
-				}
+// exitCode returns the exit code for reports.
+func exitCode(reports []report) int {
+	code := exitClean
+	for _, r := range reports {
+		if r.Error != nil {
+			return exitFailure
+		}
+		if r.Changed {
+			code = exitChanged
+		}
+	}
+	return code
+}
 
-				if strings.HasPrefix(name, "new") {
-					return name, constructorFuncWeight
-				}
+// printErrors writes the errors in reports to w, one per file.
+func printErrors(w io.Writer, reports []report) {
+	for _, r := range reports {
+		if r.Error != nil {
+			fmt.Fprintln(w, r.Error.Message)
+		}
+	}
+}
 
-				if firstUpper(name) {
-					weight := exportedFuncWeight
-					if strings.HasPrefix(name, "New") {
-						weight--
-					}
-					return name, weight
-				}
+// fatal logs v and exits with code.
+func fatal(code int, v ...any) {
+	log.Print(v...)
+	exit(code)
+}
 
-				return name, funcWeight
-			}
+// fatalf logs a formatted message and exits with code.
+func fatalf(code int, format string, v ...any) {
+	log.Printf(format, v...)
+	exit(code)
+}
 
-			// This is a method. We want that below the receiver type definition, if possible.
-			return fmt.Sprintf("%s.%s", fr, name), typeWeight
+// output holds how the results are written.
+type output struct {
+	write     bool   // Write the files, -w.
+	format    string // Report the files in this -format.
+	diff      bool   // Diff the files, -d.
+	patch     bool   // Write the diffs as a patch, -patch.
+	manifest  bool   // Hash the files for -manifest.
+	positions bool   // Map the declaration lines for -positions.
+	quiet     bool   // Print nothing but errors, -q.
+	color     colorizer
+}
 
-		}
+// fileJob is a file to process with the config resolved for it.
+type fileJob struct {
+	filename string
+	cfg      config
 
-		genName := func(d dst.Decl) (string, int) {
-			m, ok := d.(*dst.GenDecl)
-			if !ok {
-				return "", -1
-			}
+	// module is the module path of the file, with -modules.
+	module string
+}
 
-			if m.Tok == token.TYPE {
-				// Return on the form receiver.____ to make sure it's grouped with the
-				// methods it owns.
-				return m.Specs[0].(*dst.TypeSpec).Name.String() + "." + magicTypeMarker, typeWeight
+// handleFiles processes files using up to n goroutines. Platform variants
+// aligned with -align are processed in order by the same goroutine, as the
+// first file in each group decides the order of the others. No new files are
+// started once ctx is done.
+//
+// It returns a report for each file processed, in the order of files. The
+// errors for single files are kept in their reports instead of stopping.
+// With emit set, each report is also passed to it, in the order of files,
+// as soon as it and those before it are done; see emitter.
+func handleFiles(ctx context.Context, files []fileJob, out output, n int, emit func(report)) ([]report, error) {
+	var batches [][]int
+	groups := make(map[string]int)
+	for i, f := range files {
+		if f.cfg.Align {
+			group := variantGroup(f.filename)
+			if j, ok := groups[group]; ok {
+				batches[j] = append(batches[j], i)
+				continue
 			}
-
-			return "", -1
-
+			groups[group] = len(batches)
 		}
+		batches = append(batches, []int{i})
+	}
 
-		name := func(d dst.Decl) (string, int) {
-			s, weight := funcName(d)
-			if weight != -1 {
-				return s, weight
-			}
+	print := !out.write && !out.diff && !out.patch && !out.quiet && out.format == formatText
+	reports := make([]report, len(files))
 
-			return genName(d)
+	if n < 1 {
+		n = 1
+	}
 
-		}
+	var e *emitter
+	if emit != nil {
+		e = newEmitter(reports, emit, streamWindow*n)
+	}
 
-		si, weighti := name(di)
-		sj, weightj := name(dj)
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, n)
+	)
 
-		if weighti == -1 && weightj == -1 {
-			return i < j
+	for _, batch := range batches {
+		e.wait(batch[0])
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
 		}
-
-		if weighti != weightj {
-			return weighti < weightj
+		if ctx.Err() != nil {
+			break
 		}
+		wg.Add(1)
+		go func(batch []int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			var align []string
+			for _, i := range batch {
+				if ctx.Err() != nil {
+					e.finish(i)
+					continue
+				}
+				var order []string
+				reports[i], order = handleJob(ctx, files[i], out, print, align)
+				if files[i].cfg.Align && align == nil {
+					align = order
+				}
+				e.finish(i)
+			}
+		}(batch)
+	}
 
-		return lesss(si, sj)
-	})
-}
+	wg.Wait()
+	e.flush()
 
-func fieldListName(list *dst.FieldList) string {
-	if list == nil {
-		return ""
-	}
-	var b strings.Builder
-	for _, v := range list.List {
-		switch xv := v.Type.(type) {
-		case *dst.StarExpr:
-			if si, ok := xv.X.(*dst.Ident); ok {
-				b.WriteString(si.Name)
-			}
-		case *dst.Ident:
-			b.WriteString(xv.Name)
+	// Drop the files not processed.
+	done := reports[:0]
+	for _, r := range reports {
+		if r.File != "" {
+			done = append(done, r)
 		}
 	}
 
-	return b.String()
+	return done, ctx.Err()
 }
 
-func less(s, t interface{}) bool {
-	strf := func(in interface{}) string {
-		switch v := in.(type) {
-		case *dst.SelectorExpr:
-			return fmt.Sprintf("%s.%s", v.X, v.Sel)
-		case *dst.Ident:
-			return v.String()
-		default:
-			panic(fmt.Sprintf("type %T", in))
+// handleJob processes the file f and returns its report and, if it was
+// sorted, the order of its declarations.
+func handleJob(ctx context.Context, f fileJob, out output, print bool, align []string) (report, []string) {
+	r, src, err := handleFile(ctx, f.filename, out.write, print, f.cfg, align)
+	var line int
+	if err == nil {
+		line = firstChangedLine(src, r.Src)
+	}
+	rep := report{File: f.filename, Module: f.module, Changed: line > 0, Moves: r.Moves, line: line}
+	if out.manifest && src != nil {
+		rep.before = contentHash(src)
+		if err == nil {
+			rep.after = contentHash(r.Src)
 		}
 	}
+	if line > 0 && out.positions {
+		rep.Positions, err = lineMappings(src, r.Src)
+	}
+	switch {
+	case line == 0 || err != nil:
+	case out.diff:
+		oldMarks, newMarks := moveMarks(r.Moves)
+		rep.text = unifiedDiff(f.filename+".orig", f.filename, src, r.Src, out.color, oldMarks, newMarks)
+	case out.patch:
+		rep.text = gitDiff(f.filename, src, r.Src)
+	case out.format == formatRDJSON:
+		rep.hunks = changedHunks(src, r.Src)
+	case out.format == formatExplain:
+		rep.text, err = explainMoves(f.filename, src, r, f.cfg)
+	}
+	if reason, ok := err.(skipError); ok {
+		rep.Skipped = string(reason)
+		return rep, nil
+	}
+	if err != nil {
+		rep.Error = newReportError(err)
+		return rep, nil
+	}
+	return rep, r.Order
+}
 
-	return lesss(strf(s), strf(t))
-
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gorder [flags] [pattern]\n")
+	fmt.Fprintf(os.Stderr, "       gorder -stdio [flags] [filename] < file\n")
+	fmt.Fprintf(os.Stderr, "       gorder config init [filename]\n")
+	fmt.Fprintf(os.Stderr, "       gorder config validate [path]\n")
+	fmt.Fprintf(os.Stderr, "       gorder config migrate [filename]\n")
+	fmt.Fprintf(os.Stderr, "       gorder report [-o filename] [patterns]\n")
+	fmt.Fprintf(os.Stderr, "       gorder lint [-json] [patterns]\n")
+	fmt.Fprintf(os.Stderr, "       gorder daemon [-socket filename]\n")
+	fmt.Fprintf(os.Stderr, "       gorder serve [-http addr]\n")
+	fmt.Fprintf(os.Stderr, "       gorder lsp [-formatting]\n")
+	fmt.Fprintf(os.Stderr, "       gorder mergetool [-marker-size n] base current other [path]\n")
+	fmt.Fprintf(os.Stderr, "       gorder split [-n] [dir]\n")
+	fmt.Fprintf(os.Stderr, "       gorder relocate [-n] [dir]\n")
+	fmt.Fprintf(os.Stderr, "       gorder merge [-n] [-by type|section|package] [-max-lines n] [dir]\n")
+	fmt.Fprintf(os.Stderr, "       gorder hook install [-force] [-pre-commit-config]\n")
+	fmt.Fprintf(os.Stderr, "       gorder hook run\n")
+	flag.PrintDefaults()
 }
 
-func lessStringers(s1, s2 fmt.Stringer) bool {
-	return lesss(s1.String(), s2.String())
+// skipError is returned by handleFile for the files left as is, holding the
+// reason; see skipReason.
+type skipError string
+
+func (e skipError) Error() string {
+	return string(e) + " file"
 }
 
-func weightAdjustment(name string) int {
-	w := 0
+// handleFile sorts filename and, with write or print set, writes the result
+// to the file or stdout. Declarations also found in align, a list of
+// declaration keys (see declKeys), are additionally ordered as in align. It
+// returns the result and the original source.
+func handleFile(ctx context.Context, filename string, write, print bool, c config, align []string) (gorder.Result, []byte, error) {
+	var perm os.FileMode = 0644
 
-	if name == magicTypeMarker {
-		w -= 5
-	}
-	// Exported funcs
-	if firstUpper(name) {
-		w -= 2
+	// The file holding the content, and written with -w.
+	path := filename
+	if fileOverlay != nil {
+		path = fileOverlay.path(filename)
 	}
 
-	// Exported constructor funcs.
-	if strings.HasPrefix(name, "New") {
-		w--
+	f, err := os.Open(path)
+	if err != nil {
+		return gorder.Result{}, nil, err
 	}
+	defer f.Close()
 
-	return w
-}
+	fi, err := f.Stat()
+	if err != nil {
+		return gorder.Result{}, nil, err
+	}
 
-func lesss(s1, s2 string) bool {
-	s1r, s1name := splitOnDot(s1)
-	s2r, s2name := splitOnDot(s2)
+	perm = fi.Mode().Perm()
 
-	if s1r != s2r {
-		// Different receiver types
-		return s1r < s2r
+	src, err := readFile(f, fi.Size())
+	if err != nil {
+		return gorder.Result{}, nil, err
 	}
 
-	s1w := 100
-	s2w := 100
+	if reason := skipReason(src, c); reason != "" {
+		if print {
+			if _, err := os.Stdout.Write(src); err != nil {
+				return gorder.Result{}, nil, err
+			}
+		}
+		return gorder.Result{}, src, skipError(reason)
+	}
 
-	s1w += weightAdjustment(s1name)
-	s2w += weightAdjustment(s2name)
+	var key string
+	if cache != nil && cacheable(c) {
+		if key, err = cache.key(src, c); err != nil {
+			return gorder.Result{}, nil, err
+		}
+		if cache.ordered(key) {
+			if print {
+				if _, err := os.Stdout.Write(src); err != nil {
+					return gorder.Result{}, nil, err
+				}
+			}
+			return gorder.Result{Src: src}, src, nil
+		}
+	}
 
-	if s1w != s2w {
-		return s1w < s2w
+	opts, err := fileOptions(filename, src, c)
+	if err != nil {
+		return gorder.Result{}, nil, err
 	}
+	opts.Align = align
 
-	var s1prefix, s2prefix string
+	r, err := gorder.ReorderContext(ctx, filename, src, opts)
+	if err != nil {
+		return gorder.Result{}, nil, fmt.Errorf("%s: %w", filename, err)
+	}
 
-	s1name, s1prefix = trimCommonPrefix(s1name)
-	s2name, s2prefix = trimCommonPrefix(s2name)
+	if key != "" && bytes.Equal(src, r.Src) {
+		cache.markOrdered(key)
+	}
 
-	if s1prefix != "" && s2prefix != "" {
-		return s1prefix < s2prefix
+	if write {
+		// Leave files in order alone, keeping their modification time.
+		if bytes.Equal(src, r.Src) {
+			return r, src, nil
+		}
+		if err := checkMoves(r, c.MaxMovesPercent); err != nil {
+			return gorder.Result{}, nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		if err := writeFile(path, r.Src, perm); err != nil {
+			return gorder.Result{}, nil, err
+		}
+		if key != "" {
+			if key, err := cache.key(r.Src, c); err == nil {
+				cache.markOrdered(key)
+			}
+		}
+	} else if print {
+		if _, err := os.Stdout.Write(r.Src); err != nil {
+			return gorder.Result{}, nil, err
+		}
 	}
 
-	return s1name < s2name
+	return r, src, nil
+}
 
+// readFile reads f, of size bytes as last seen, in one allocation unless it
+// grew since.
+func readFile(f *os.File, size int64) ([]byte, error) {
+	b := bytes.NewBuffer(make([]byte, 0, size+bytes.MinRead))
+	_, err := b.ReadFrom(f)
+	return b.Bytes(), err
 }
 
-var commonPrefixes = []string{"Is", "Has", "Get", "All", "Create", "New", "Err", "Error", "Init", "Find", "Set", "Render"}
+// minMovesChecked is the number of declarations below which checkMoves
+// lets any reordering through, as in small files a few moves are a large
+// share.
+const minMovesChecked = 5
+
+// checkMoves returns an error if more than maxPercent of the declarations,
+// imports aside, move in r, to guard files structured on purpose against
+// being shredded by -w with the wrong settings.
+func checkMoves(r gorder.Result, maxPercent int) error {
+	if maxPercent <= 0 {
+		return nil
+	}
+	var total int
+	for _, key := range r.Order {
+		if !strings.HasPrefix(key, "import") {
+			total++
+		}
+	}
+	if total < minMovesChecked || 100*len(r.Moves) <= maxPercent*total {
+		return nil
+	}
+	return fmt.Errorf("%d of %d declarations would move (%d%%), more than -max-moves-percent=%d, leaving the file as is; review with -d and set -force to write it",
+		len(r.Moves), total, 100*len(r.Moves)/total, maxPercent)
+}
 
-func trimCommonPrefix(s string) (string, string) {
-	for _, prefix := range commonPrefixes {
-		if strings.HasPrefix(s, prefix) {
-			return prefix, strings.TrimPrefix(s, prefix)
+// fileOptions returns the options for the file filename with the source
+// src, applying its //gorder:config directive to c.
+func fileOptions(filename string, src []byte, c config) (gorder.Options, error) {
+	if c.Directives && bytes.Contains(src, []byte(gorder.DirectivePrefix+directiveConfig)) {
+		args, found, err := gorder.FileDirective(src, directiveConfig)
+		if err != nil {
+			return gorder.Options{}, err
 		}
-		if strings.HasPrefix(s, strings.ToLower(prefix)) {
-			return prefix, strings.TrimPrefix(s, strings.ToLower(prefix))
+		if found {
+			if err := applyConfigDirective(args, &c); err != nil {
+				return gorder.Options{}, fmt.Errorf("%s: %s", filename, err)
+			}
 		}
 	}
 
-	return "", s
-
+	opts, err := c.options()
+	if err != nil {
+		return gorder.Options{}, fmt.Errorf("%s: %s", filename, err)
+	}
+	return opts, nil
 }
 
-func preserveOrder(decl dst.Decl) bool {
-	switch v := decl.(type) {
-	case *dst.GenDecl:
-		return v.Tok == token.PACKAGE || v.Tok == token.IMPORT
-	default:
-		return false
+// firstChangedLine returns the first line that differs in a and b, or 0 if
+// they are equal.
+func firstChangedLine(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return 0
+	}
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
 	}
+	return bytes.Count(a[:i], []byte("\n")) + 1
 }
 
-func isFuncDecl(decl dst.Decl) bool {
-	switch decl.(type) {
-	case *dst.FuncDecl:
-		return true
-	default:
-		return false
+// writeFile replaces filename with data. The data is written to a temporary
+// file in the same directory that is then renamed, so filename is never left
+// half written.
+func writeFile(filename string, data []byte, perm os.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".gorder")
+	if err != nil {
+		return err
 	}
-}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
 
-func splitOnDot(name string) (string, string) {
-	parts := strings.Split(name, ".")
-	if len(parts) > 2 {
-		panic("too many")
+	if _, err := f.Write(data); err != nil {
+		return err
 	}
-	if len(parts) == 1 {
-		return "", name
+	if err := f.Chmod(perm); err != nil {
+		return err
 	}
-
-	return parts[0], parts[1]
-
-}
-
-func firstUpper(name string) bool {
-	for _, r := range name {
-		return unicode.IsUpper(r)
+	if err := f.Close(); err != nil {
+		return err
 	}
-	return false
+
+	return os.Rename(f.Name(), filename)
 }
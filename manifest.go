@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"runtime/debug"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// manifest lists what a run read and wrote, as written with -manifest.
+type manifest struct {
+	Tool  manifestTool   `json:"tool"`
+	Write bool           `json:"write"`
+	Files []manifestFile `json:"files"`
+}
+
+type manifestTool struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Revision string `json:"revision,omitempty"`
+	Go       string `json:"go"`
+}
+
+type manifestFile struct {
+	Path    string `json:"path"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+	Changed bool   `json:"changed"`
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	// Config is the config resolved for the file, not counting its
+	// //gorder:config directive.
+	Config map[string]any `json:"config"`
+}
+
+// writeManifest writes the manifest for the files and their reports to
+// filename.
+func writeManifest(filename string, files []fileJob, reports []report, write bool) error {
+	m := manifest{Tool: toolInfo(), Write: write, Files: []manifestFile{}}
+
+	configs := make(map[string]config, len(files))
+	for _, f := range files {
+		configs[f.filename] = f.cfg
+	}
+
+	for _, r := range reports {
+		c, err := configMap(configs[r.File])
+		if err != nil {
+			return err
+		}
+		f := manifestFile{
+			Path:    r.File,
+			Before:  r.before,
+			After:   r.after,
+			Changed: r.Changed,
+			Skipped: r.Skipped,
+			Config:  c,
+		}
+		if r.Error != nil {
+			f.Error = r.Error.Message
+		}
+		m.Files = append(m.Files, f)
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0644)
+}
+
+// toolInfo returns the name and version of gorder from the build info.
+func toolInfo() manifestTool {
+	t := manifestTool{Name: "gorder", Version: "(devel)"}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return t
+	}
+	t.Go = info.GoVersion
+	if v := info.Main.Version; v != "" {
+		t.Version = v
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			t.Revision = s.Value
+		}
+	}
+	return t
+}
+
+// configMap returns c keyed as in the config files.
+func configMap(c config) (map[string]any, error) {
+	b, err := toml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := toml.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// contentHash returns the SHA-256 of b, prefixed with sha256:.
+func contentHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// The -by values of gorder merge.
+const (
+	mergeByType    = "type"
+	mergeBySection = "section"
+	mergeByPackage = "package"
+)
+
+// runMerge runs the merge subcommand, moving the declarations of the small
+// files of the package in dir to fewer files, as grouped by -by. It returns
+// exitChanged if declarations moved.
+func runMerge(r *configResolver, args []string) (int, error) {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	dryRun := fs.Bool("n", false, "print the moves without writing the files")
+	by := fs.String("by", mergeByType, "the files to merge into: type, one per type with its constructors and methods and one named for the package for the rest; section, types.go, funcs.go and vars.go; or package, one named for the package")
+	maxLines := fs.Int("max-lines", 50, "merge the files with at most this many lines")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage, err
+	}
+	if fs.NArg() > 1 {
+		return exitUsage, errors.New("usage: gorder merge [-n] [-by type|section|package] [-max-lines n] [dir]")
+	}
+	switch *by {
+	case mergeByType, mergeBySection, mergeByPackage:
+	default:
+		return exitUsage, fmt.Errorf("invalid -by value %q", *by)
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	c, err := r.resolve(dir)
+	if err != nil {
+		return exitUsage, err
+	}
+	opts, err := c.options()
+	if err != nil {
+		return exitUsage, err
+	}
+	p, err := loadPackage(dir, c)
+	if err != nil {
+		return exitFailure, err
+	}
+
+	small := func(name string) bool {
+		return p.movable[name] && p.lines[name] <= *maxLines
+	}
+	_, decls, files := typeDecls(p, opts, false)
+	types := make(map[dst.Decl]string)
+	for t, ds := range decls {
+		for _, d := range ds {
+			types[d] = t
+		}
+	}
+	pkgFile := p.pkg.Name + ".go"
+
+	// target returns the file d in the file name merges into.
+	target := func(d dst.Decl, name string) string {
+		t, ok := types[d]
+		switch *by {
+		case mergeByPackage:
+			return pkgFile
+		case mergeBySection:
+			switch d := d.(type) {
+			case *dst.GenDecl:
+				if d.Tok == token.TYPE {
+					return "types.go"
+				}
+				return "vars.go"
+			case *dst.FuncDecl:
+				if ok {
+					return "types.go"
+				}
+				if d.Recv != nil {
+					// The method of a type that stays.
+					return name
+				}
+			}
+			return "funcs.go"
+		}
+		if ok {
+			if from := files[decls[t][0]]; !small(from) {
+				// The type stays in its file.
+				return from
+			}
+			return typeFilename(t)
+		}
+		if f, isFunc := d.(*dst.FuncDecl); isFunc && f.Recv != nil {
+			return name
+		}
+		return pkgFile
+	}
+
+	moves := mergeMoves(p, small, target)
+	return applyMoves(p, moves, c, *dryRun)
+}
+
+// mergeMoves returns the moves of the declarations in the small files of p
+// to their target files. The files merged into must end up with the
+// declarations of two files at least, or else the declarations stay, not
+// to merely rename a file.
+func mergeMoves(p *goPackage, small func(name string) bool, target func(d dst.Decl, name string) string) []declMove {
+	var (
+		targets []string
+		planned = make(map[string][]declMove)
+	)
+	for _, name := range p.names {
+		if !small(name) {
+			continue
+		}
+		for _, d := range p.files[name].Decls {
+			// The files of the //go:embed variables import "embed".
+			if g, ok := d.(*dst.GenDecl); ok && (g.Tok == token.IMPORT || embeds(g)) {
+				continue
+			}
+			to := target(d, name)
+			if _, ok := planned[to]; !ok {
+				targets = append(targets, to)
+			}
+			planned[to] = append(planned[to], declMove{decl: d, from: name, to: to})
+		}
+	}
+
+	var moves []declMove
+	for _, to := range targets {
+		from := make(map[string]bool)
+		if _, ok := p.files[to]; ok {
+			from[to] = true
+		}
+		for _, m := range planned[to] {
+			from[m.from] = true
+		}
+		if len(from) < 2 {
+			continue
+		}
+		if !p.canCreate(to) {
+			fmt.Fprintf(os.Stderr, "gorder: cannot merge into %s\n", to)
+			continue
+		}
+		for _, m := range planned[to] {
+			if m.from != to {
+				moves = append(moves, m)
+			}
+		}
+	}
+	slices.SortStableFunc(moves, func(a, b declMove) int {
+		return p.order[a.decl] - p.order[b.decl]
+	})
+	return moves
+}
+
+// embeds reports whether g has a //go:embed directive.
+func embeds(g *dst.GenDecl) bool {
+	for _, line := range g.Decs.Start {
+		if strings.HasPrefix(line, "//go:embed ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// runMergetool runs the mergetool subcommand, a git merge driver merging
+// the base, current and other versions of a file, given as %O %A %B, and
+// optionally its path, %P, which selects the config. The three versions
+// are reordered before merging them with git merge-file, so conflicts only
+// caused by declarations moved on one side go away. The result is written
+// to the current version and reordered again if clean. It returns
+// exitClean if the merge is clean, exitChanged if conflicts remain.
+func runMergetool(r *configResolver, args []string) (int, error) {
+	fs := flag.NewFlagSet("mergetool", flag.ContinueOnError)
+	markerSize := fs.Int("marker-size", 7, "length of the conflict markers, %L in the driver command")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage, err
+	}
+	if fs.NArg() != 3 && fs.NArg() != 4 {
+		return exitUsage, errors.New("usage: gorder mergetool [-marker-size n] base current other [path]")
+	}
+	base, current, other := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+	path := fs.Arg(3)
+
+	dir := "."
+	if path != "" {
+		dir = filepath.Dir(path)
+	}
+	c, err := r.resolve(dir)
+	if err != nil {
+		return exitUsage, err
+	}
+	name := path
+	if name == "" {
+		name = current
+	}
+
+	// The reordered versions, in temporary files.
+	files := []string{current, base, other}
+	for i, filename := range files {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return exitFailure, err
+		}
+		f, err := os.CreateTemp("", "gorder-merge-*.go")
+		if err != nil {
+			return exitFailure, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(reorderOrKeep(name, src, c))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return exitFailure, err
+		}
+		files[i] = f.Name()
+	}
+
+	label := name
+	cmd := exec.Command("git", "merge-file", "-p", "--marker-size="+strconv.Itoa(*markerSize),
+		"-L", label, "-L", label+" (base)", "-L", label+" (other)", files[0], files[1], files[2])
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	merged, err := cmd.Output()
+	conflicts := false
+	if err != nil {
+		// The exit code is the number of conflicts, if positive.
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() < 0 || exitErr.ExitCode() > 127 {
+			return exitFailure, fmt.Errorf("git merge-file: %s", bytes.TrimSpace(stderr.Bytes()))
+		}
+		conflicts = true
+	}
+
+	if !conflicts {
+		// Declarations added on both sides may need to move.
+		merged = reorderOrKeep(name, merged, c)
+	}
+	if err := os.WriteFile(current, merged, 0644); err != nil {
+		return exitFailure, err
+	}
+
+	if conflicts {
+		return exitChanged, nil
+	}
+	return exitClean, nil
+}
+
+// reorderOrKeep returns src reordered with c, or as is if it is skipped or
+// cannot be reordered, e.g. with a syntax error, to merge it as it is.
+func reorderOrKeep(filename string, src []byte, c config) []byte {
+	if skipReason(src, c) != "" {
+		return src
+	}
+	opts, err := fileOptions(filename, src, c)
+	if err != nil {
+		return src
+	}
+	res, err := gorder.Reorder(filename, src, opts)
+	if err != nil {
+		return src
+	}
+	return res.Src
+}
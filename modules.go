@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/mod/modfile"
+)
+
+// goModule is the module of the files in a run with -modules.
+type goModule struct {
+	// Path is the module path, "" for files outside any module.
+	Path string
+
+	// Dir is the directory holding the go.mod.
+	Dir string
+}
+
+// moduleResolver finds the modules of directories.
+type moduleResolver struct {
+	cache map[string]goModule
+}
+
+func newModuleResolver() *moduleResolver {
+	return &moduleResolver{cache: make(map[string]goModule)}
+}
+
+// module returns the module of the files in dir, from the go.mod in dir or
+// its closest parent holding one.
+func (r *moduleResolver) module(dir string) (goModule, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return goModule{}, err
+	}
+	if m, ok := r.cache[dir]; ok {
+		return m, nil
+	}
+
+	var m goModule
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	switch {
+	case err == nil:
+		m = goModule{Path: modfile.ModulePath(b), Dir: dir}
+		if m.Path == "" {
+			return goModule{}, fmt.Errorf("%s: no module path", filepath.Join(dir, "go.mod"))
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return goModule{}, err
+	default:
+		if parent := filepath.Dir(dir); parent != dir {
+			if m, err = r.module(parent); err != nil {
+				return goModule{}, err
+			}
+		}
+	}
+
+	r.cache[dir] = m
+	return m, nil
+}
+
+// workspaceFiles returns the Go files of the modules used by the go.work in
+// dir, or ok false if there is none. As with the go command, the files of
+// nested modules not in the workspace are left out.
+func workspaceFiles(r *moduleResolver, dir string) (filenames []string, ok bool, err error) {
+	filename := filepath.Join(dir, "go.work")
+	b, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	work, err := modfile.ParseWork(filename, b, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, use := range work.Use {
+		moddir := use.Path
+		if !filepath.IsAbs(moddir) {
+			moddir = filepath.Join(dir, filepath.FromSlash(moddir))
+		}
+		files, err := expandPattern(filepath.Join(moddir, "..."))
+		if err != nil {
+			return nil, false, err
+		}
+		abs, err := filepath.Abs(moddir)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, f := range files {
+			m, err := r.module(filepath.Dir(f))
+			if err != nil {
+				return nil, false, err
+			}
+			if m.Dir == abs {
+				filenames = append(filenames, f)
+			}
+		}
+	}
+	slices.Sort(filenames)
+	return slices.Compact(filenames), true, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// overlay maps the absolute paths of files to the files holding their
+// content, as the -overlay file of go build. An empty replacement removes
+// the file.
+type overlay map[string]string
+
+// fileOverlay is the -overlay, if set.
+var fileOverlay overlay
+
+// readOverlay reads the overlay file filename, with relative paths resolved
+// against the current directory as with go build.
+func readOverlay(filename string) (overlay, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		Replace map[string]string
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+
+	o := make(overlay)
+	for from, to := range v.Replace {
+		if from, err = filepath.Abs(from); err != nil {
+			return nil, err
+		}
+		if to != "" {
+			if to, err = filepath.Abs(to); err != nil {
+				return nil, err
+			}
+		}
+		o[from] = to
+	}
+	return o, nil
+}
+
+// path returns the file holding the content of filename, filename itself if
+// it is not replaced.
+func (o overlay) path(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		if to, ok := o[abs]; ok && to != "" {
+			return to
+		}
+	}
+	return filename
+}
+
+// removed reports whether the overlay removes filename.
+func (o overlay) removed(filename string) bool {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return false
+	}
+	to, ok := o[abs]
+	return ok && to == ""
+}
+
+// expand returns filenames, the files matching pattern on disk, without the
+// files the overlay removes and with those it adds matching pattern, if
+// set, sorted.
+func (o overlay) expand(pattern string, filenames []string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	for _, filename := range filenames {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return nil, err
+		}
+		seen[abs] = true
+		if !o.removed(filename) {
+			result = append(result, filename)
+		}
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	for from, to := range o {
+		if pattern == "" || seen[from] || to == "" || !strings.HasSuffix(from, ".go") {
+			continue
+		}
+		name := from
+		if !filepath.IsAbs(pattern) {
+			if rel, err := filepath.Rel(cwd, from); err == nil && !strings.HasPrefix(rel, "..") {
+				name = rel
+			}
+		}
+		ok, err := matchPattern(pattern, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, name)
+		}
+	}
+	slices.Sort(result)
+	return result, nil
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	gofmt "go/format"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"golang.org/x/tools/go/packages"
+)
+
+// goPackage is a package loaded with its type information, to move
+// declarations between its files. The remote identifiers in the decorated
+// files carry their import path, so the imports of the files are updated
+// as the declarations move.
+type goPackage struct {
+	dir string
+	pkg *packages.Package
+
+	// The files by base name, and the names in order.
+	files map[string]*dst.File
+	names []string
+
+	// The files declarations can move to or from, by base name.
+	movable map[string]bool
+
+	// The number of lines of the loaded files.
+	lines map[string]int
+
+	// The license headers of the files: the comments above the package
+	// clause but its doc comment.
+	headers map[string][]string
+
+	// The aliases of the named imports of the files, by path.
+	aliases map[string]map[string]string
+
+	// The files changed and the files added.
+	changed map[string]bool
+	added   map[string]bool
+
+	// The positions of the declarations in the loaded files, to keep their
+	// order as they move.
+	order map[dst.Decl]int
+}
+
+// loadPackage loads the package in dir, leaving out its tests. The files
+// declarations can move to or from are those not excluded by c, generated,
+// using cgo or constrained to some builds, by name or a //go:build line.
+func loadPackage(dir string, c config) (*goPackage, error) {
+	// The dependencies are type checked from source, not export data, for
+	// they may have been built by another Go release.
+	pkgs, err := packages.Load(&packages.Config{Dir: dir, Mode: packages.LoadSyntax | packages.NeedDeps}, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s: expected one package, found %d", dir, len(pkgs))
+	}
+	pkg := pkgs[0]
+	if len(pkg.Errors) > 0 {
+		return nil, errors.New(pkg.Errors[0].Error())
+	}
+	if len(pkg.GoFiles) == 0 {
+		return nil, fmt.Errorf("%s: no Go files", dir)
+	}
+
+	p := &goPackage{
+		dir:     filepath.Dir(pkg.GoFiles[0]),
+		pkg:     pkg,
+		files:   make(map[string]*dst.File),
+		movable: make(map[string]bool),
+		lines:   make(map[string]int),
+		headers: make(map[string][]string),
+		aliases: make(map[string]map[string]string),
+		changed: make(map[string]bool),
+		added:   make(map[string]bool),
+		order:   make(map[dst.Decl]int),
+	}
+	dec := decorator.NewDecoratorFromPackage(pkg)
+	for _, af := range pkg.Syntax {
+		// Leave out the files generated by cgo.
+		filename := pkg.Fset.File(af.Pos()).Name()
+		if !slices.Contains(pkg.GoFiles, filename) {
+			continue
+		}
+		file, err := dec.DecorateFile(af)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(filename)
+		p.files[name] = file
+		p.names = append(p.names, name)
+		p.lines[name] = pkg.Fset.File(af.Pos()).LineCount()
+		p.headers[name] = licenseHeader(af)
+		p.aliases[name] = importAliases(af)
+		for _, d := range file.Decls {
+			p.order[d] = len(p.order)
+		}
+
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		skip, err := c.excluded(filename)
+		if err != nil {
+			return nil, err
+		}
+		p.movable[name] = !skip && !ast.IsGenerated(af) && !isCgo(src) && !constrainedName(name) && !hasBuildLine(af)
+	}
+	slices.Sort(p.names)
+	return p, nil
+}
+
+// canCreate reports whether a declaration can move to a new file with the
+// name, or an existing one it can move to.
+func (p *goPackage) canCreate(name string) bool {
+	if _, ok := p.files[name]; ok {
+		return p.movable[name]
+	}
+	if constrainedName(name) {
+		return false
+	}
+	// A file left out of the loaded package, e.g. for another platform.
+	_, err := os.Stat(filepath.Join(p.dir, name))
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// move moves d from the file named from to the end of the file named to,
+// creating it with the license header of from if needed.
+func (p *goPackage) move(d dst.Decl, from, to string) {
+	src := p.files[from]
+	src.Decls = slices.DeleteFunc(src.Decls, func(e dst.Decl) bool { return e == d })
+	p.changed[from] = true
+
+	dest, ok := p.files[to]
+	if !ok {
+		dest = &dst.File{Name: dst.NewIdent(src.Name.Name)}
+		dest.Decs.Start = slices.Clone(p.headers[from])
+		p.files[to] = dest
+		p.names = append(p.names, to)
+		slices.Sort(p.names)
+		p.movable[to] = true
+		p.headers[to] = p.headers[from]
+		p.aliases[to] = make(map[string]string)
+		p.added[to] = true
+	}
+	// The imports moved along keep their aliases.
+	for path, alias := range p.aliases[from] {
+		if _, ok := p.aliases[to][path]; !ok {
+			p.aliases[to][path] = alias
+		}
+	}
+	d.Decorations().Before = dst.EmptyLine
+	dest.Decls = append(dest.Decls, d)
+	p.changed[to] = true
+}
+
+// sortedDecls sorts decls by their position in the loaded files.
+func (p *goPackage) sortedDecls(decls []dst.Decl) {
+	slices.SortStableFunc(decls, func(a, b dst.Decl) int {
+		return p.order[a] - p.order[b]
+	})
+}
+
+// write writes the changed files, reordered with c, and removes those left
+// with no declarations or comments of their own. It returns the files
+// removed.
+func (p *goPackage) write(c config) ([]string, error) {
+	var removed []string
+	for _, name := range p.names {
+		if !p.changed[name] {
+			continue
+		}
+		filename := filepath.Join(p.dir, name)
+		src, empty, err := p.restore(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err)
+		}
+		if empty {
+			if !p.added[name] {
+				if err := os.Remove(filename); err != nil {
+					return nil, err
+				}
+				removed = append(removed, name)
+			}
+			continue
+		}
+		src = reorderOrKeep(filename, src, c)
+		if err := os.WriteFile(filename, src, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// restore returns the source of the file with the name, with its imports
+// updated, and whether it is left with nothing but its license header,
+// package clause and imports, none blank.
+func (p *goPackage) restore(name string) ([]byte, bool, error) {
+	r := decorator.NewRestorerWithImports(p.pkg.PkgPath, packageResolver{p.pkg.Imports})
+	fr := r.FileRestorer()
+	fr.Name = name
+	for path, alias := range p.aliases[name] {
+		fr.Alias[path] = alias
+	}
+	af, err := fr.RestoreFile(p.files[name])
+	if err != nil {
+		return nil, false, err
+	}
+	var buf bytes.Buffer
+	if err := gofmt.Node(&buf, r.Fset, af); err != nil {
+		return nil, false, err
+	}
+
+	empty := af.Doc == nil
+	for _, imp := range af.Imports {
+		if imp.Name != nil && imp.Name.Name == "_" {
+			empty = false
+		}
+	}
+	for _, d := range af.Decls {
+		if g, ok := d.(*ast.GenDecl); !ok || g.Tok != token.IMPORT {
+			empty = false
+		}
+	}
+	for _, cg := range af.Comments {
+		if cg.Pos() > af.Package {
+			empty = false
+		}
+	}
+	return buf.Bytes(), empty, nil
+}
+
+// packageResolver resolves the names of the packages imported by a loaded
+// package.
+type packageResolver struct {
+	imports map[string]*packages.Package
+}
+
+func (r packageResolver) ResolvePackage(path string) (string, error) {
+	if pkg, ok := r.imports[path]; ok && pkg.Name != "" {
+		return pkg.Name, nil
+	}
+	return "", fmt.Errorf("package %s not imported", path)
+}
+
+// licenseHeader returns the comments of f above its package clause, but its
+// doc comment and build constraints, as decoration lines.
+func licenseHeader(f *ast.File) []string {
+	var lines []string
+	for _, cg := range f.Comments {
+		if cg.Pos() >= f.Package || cg == f.Doc {
+			break
+		}
+		if isBuildLine(cg.List[0].Text) {
+			continue
+		}
+		for _, c := range cg.List {
+			lines = append(lines, c.Text)
+		}
+		lines = append(lines, "\n")
+	}
+	return lines
+}
+
+// importAliases returns the aliases of the named imports of f, by path,
+// leaving out the blank imports, which stay in f.
+func importAliases(f *ast.File) map[string]string {
+	aliases := make(map[string]string)
+	for _, imp := range f.Imports {
+		if imp.Name == nil || imp.Name.Name == "_" {
+			continue
+		}
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		aliases[path] = imp.Name.Name
+	}
+	return aliases
+}
+
+// hasBuildLine reports whether f has a //go:build or // +build line.
+func hasBuildLine(f *ast.File) bool {
+	for _, cg := range f.Comments {
+		if cg.Pos() >= f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if isBuildLine(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isBuildLine(line string) bool {
+	return constraint.IsGoBuild(line) || constraint.IsPlusBuild(line)
+}
+
+// constrainedName reports whether the go command would leave out a file
+// with the name in some builds, e.g. foo_linux.go or foo_test.go, or
+// always, e.g. _foo.go.
+func constrainedName(name string) bool {
+	if strings.HasSuffix(name, "_test.go") {
+		return true
+	}
+	// No file name suffix matches this platform.
+	ctxt := build.Default
+	ctxt.GOOS, ctxt.GOARCH = "none", "none"
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("package p\n")), nil
+	}
+	ok, err := ctxt.MatchFile(".", name)
+	return err != nil || !ok
+}
+
+// typeFilename returns the file name for the type with the name, e.g.
+// http_server.go for HTTPServer.
+func typeFilename(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && next) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String() + ".go"
+}
+
+// declName returns the name of d for the messages, e.g. Foo, NewFoo or
+// Foo.Close.
+func declName(d dst.Decl) string {
+	switch d := d.(type) {
+	case *dst.FuncDecl:
+		if recv := receiverName(d); recv != "" {
+			return recv + "." + d.Name.Name
+		}
+		return d.Name.Name
+	case *dst.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *dst.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *dst.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// receiverName returns the name of the receiver type of f, or "" if f is
+// not a method.
+func receiverName(f *dst.FuncDecl) string {
+	if f.Recv == nil || len(f.Recv.List) == 0 {
+		return ""
+	}
+	return baseTypeName(f.Recv.List[0].Type)
+}
+
+// baseTypeName returns the name of the local type e is, points to or
+// instantiates, or "".
+func baseTypeName(e dst.Expr) string {
+	for {
+		switch t := e.(type) {
+		case *dst.StarExpr:
+			e = t.X
+		case *dst.IndexExpr:
+			e = t.X
+		case *dst.IndexListExpr:
+			e = t.X
+		case *dst.ParenExpr:
+			e = t.X
+		case *dst.Ident:
+			if t.Path != "" {
+				return ""
+			}
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// expandPattern returns the files matching pattern, a glob pattern or a
+// directory followed by /..., as in ./..., for all Go files in and below it.
+// As with the go command, directories named vendor or testdata or starting
+// with . or _ are skipped.
+func expandPattern(pattern string) ([]string, error) {
+	root, ok := patternRoot(pattern)
+	if !ok {
+		return filepath.Glob(pattern)
+	}
+
+	var filenames []string
+	err := filepath.WalkDir(filepath.FromSlash(root), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != filepath.FromSlash(root) && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".go") {
+			filenames = append(filenames, path)
+		}
+		return nil
+	})
+	return filenames, err
+}
+
+// matchPattern reports whether pattern, as for expandPattern, matches the
+// file filename, which need not exist.
+func matchPattern(pattern, filename string) (bool, error) {
+	root, ok := patternRoot(pattern)
+	if !ok {
+		return filepath.Match(pattern, filename)
+	}
+	if !strings.HasSuffix(filename, ".go") {
+		return false, nil
+	}
+
+	rel := filepath.ToSlash(filepath.Clean(filename))
+	if root != "." {
+		var found bool
+		if rel, found = strings.CutPrefix(rel, strings.TrimSuffix(path.Clean(root), "/")+"/"); !found {
+			return false, nil
+		}
+	}
+	dirs := strings.Split(rel, "/")
+	for _, name := range dirs[:len(dirs)-1] {
+		if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// patternRoot returns the directory of pattern, with slashes, if it is on
+// the form dir/..., ok false for a glob pattern.
+func patternRoot(pattern string) (root string, ok bool) {
+	root, ok = strings.CutSuffix(filepath.ToSlash(pattern), "...")
+	if !ok || (root != "" && !strings.HasSuffix(root, "/")) {
+		return "", false
+	}
+	switch root {
+	case "":
+		return ".", true
+	case "/":
+		return root, true
+	default:
+		return strings.TrimSuffix(root, "/"), true
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// lineMapping maps the lines of a top-level declaration, with its doc
+// comment, before and after reordering, for editors to restore the
+// cursor, selections, breakpoints and folds.
+type lineMapping struct {
+	Old gorder.LineRange `json:"old"`
+	New gorder.LineRange `json:"new"`
+}
+
+// lineMappings returns the mappings of the top-level declarations in src at
+// other lines in out. The declarations are paired by kind and name, so
+// those merged or removed, e.g. import declarations with -imports, are left
+// out.
+func lineMappings(src, out []byte) ([]lineMapping, error) {
+	oldFset, newFset := token.NewFileSet(), token.NewFileSet()
+	oldFile, err := parser.ParseFile(oldFset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	newFile, err := parser.ParseFile(newFset, "", out, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	// The new declarations by key, in order, for duplicates such as init.
+	after := make(map[string][]ast.Decl)
+	for _, d := range newFile.Decls {
+		key := mappingKey(d)
+		after[key] = append(after[key], d)
+	}
+
+	mappings := []lineMapping{}
+	for _, d := range oldFile.Decls {
+		key := mappingKey(d)
+		if len(after[key]) == 0 {
+			continue
+		}
+		m := lineMapping{Old: declRange(oldFset, d), New: declRange(newFset, after[key][0])}
+		after[key] = after[key][1:]
+		if m.Old != m.New {
+			mappings = append(mappings, m)
+		}
+	}
+	return mappings, nil
+}
+
+// mappingKey returns the kind and names of d.
+func mappingKey(d ast.Decl) string {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return "method " + types.ExprString(d.Recv.List[0].Type) + "." + d.Name.Name
+		}
+		return "func " + d.Name.Name
+	case *ast.GenDecl:
+		names := []string{d.Tok.String()}
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.ImportSpec:
+				names = append(names, s.Path.Value)
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			}
+		}
+		return strings.Join(names, " ")
+	}
+	return ""
+}
+
+// declRange returns the lines of d, with its doc comment.
+func declRange(fset *token.FileSet, d ast.Decl) gorder.LineRange {
+	pos := d.Pos()
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			pos = d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			pos = d.Doc.Pos()
+		}
+	}
+	return gorder.LineRange{Start: fset.Position(pos).Line, End: fset.Position(d.End()).Line}
+}
+
+// writePositions writes the line mappings of the changed files in reports
+// to filename, as JSON.
+func writePositions(filename string, reports []report) error {
+	type filePositions struct {
+		File      string        `json:"file"`
+		Positions []lineMapping `json:"positions"`
+	}
+	files := []filePositions{}
+	for _, r := range reports {
+		if r.Changed {
+			files = append(files, filePositions{File: r.File, Positions: r.Positions})
+		}
+	}
+	b, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0644)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// stopProfiles stops the profiles started with startProfiles and writes the
+// memory profile. It is called before exiting.
+var stopProfiles = func() {}
+
+// startProfiles starts the CPU profile and the execution trace, written to
+// the files cpu and tr, and sets up the memory profile written to mem on
+// exit. Empty names are skipped.
+func startProfiles(cpu, mem, tr string) error {
+	var stops []func() error
+
+	if cpu != "" {
+		f, err := os.Create(cpu)
+		if err != nil {
+			return err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		})
+	}
+
+	if tr != "" {
+		f, err := os.Create(tr)
+		if err != nil {
+			return err
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return err
+		}
+		stops = append(stops, func() error {
+			trace.Stop()
+			return f.Close()
+		})
+	}
+
+	if mem != "" {
+		f, err := os.Create(mem)
+		if err != nil {
+			return err
+		}
+		stops = append(stops, func() error {
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		})
+	}
+
+	stopProfiles = func() {
+		stopProfiles = func() {}
+		for _, stop := range stops {
+			if err := stop(); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+	return nil
+}
+
+// exit stops the profiles and exits with code.
+func exit(code int) {
+	stopProfiles()
+	os.Exit(code)
+}
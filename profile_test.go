@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bep/gorder/gorder"
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+)
+
+// corpusFiles returns the files of the benchmark corpus, large files from
+// the Go source tree, see testdata/corpus/LICENSE, and their total size.
+func corpusFiles(b *testing.B) ([]string, int64) {
+	filenames, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.go"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	if len(filenames) == 0 {
+		b.Fatal("no files in testdata/corpus")
+	}
+	var size int64
+	for _, filename := range filenames {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size += fi.Size()
+	}
+	return filenames, size
+}
+
+// Both benchmarks handle the whole corpus in each iteration, so their
+// throughput and allocations compare.
+
+func BenchmarkSortDecls(b *testing.B) {
+	filenames, size := corpusFiles(b)
+	opts := gorder.DefaultOptions()
+
+	var files [][]dst.Decl
+	for _, filename := range filenames {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			b.Fatal(err)
+		}
+		file, err := decorator.Parse(src)
+		if err != nil {
+			b.Fatal(err)
+		}
+		files = append(files, file.Decls)
+	}
+
+	decls := make([][]dst.Decl, len(files))
+	for i, f := range files {
+		decls[i] = make([]dst.Decl, len(f))
+	}
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, f := range files {
+			// Sort the declarations in their order in the file each time.
+			copy(decls[j], f)
+			gorder.SortDecls(decls[j], opts)
+		}
+	}
+}
+
+func BenchmarkHandleFile(b *testing.B) {
+	filenames, size := corpusFiles(b)
+	c := defaultConfig()
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, filename := range filenames {
+			if _, _, err := handleFile(context.Background(), filename, false, false, c, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
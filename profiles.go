@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// profiles are named presets of settings. A profile is applied on top of the
+// defaults; config files and flags override it.
+var profiles = map[string]func(c *config){
+	// hugo is the default convention: main and flags first, then exported
+	// functions, constructors, types with their methods and other functions.
+	"hugo": func(c *config) {},
+
+	// godoc follows the order of the go doc output: functions before
+	// types, each type followed by the functions returning it and its
+	// methods, exported types before unexported ones, plain names.
+	"godoc": func(c *config) {
+		c.Mode = gorder.ModeGodoc
+		c.ExportedTypes = true
+		c.Prefixes = nil
+		c.Weights = gorder.Weights{
+			ErrorsBottom: 300,
+			Type:         200,
+			Func:         100,
+			Constructor:  50,
+			Exported:     30,
+			Main:         10,
+			Flags:        9,
+			ErrorsTop:    5,
+		}
+	},
+
+	// alpha sorts functions and types by name only, keeping methods below
+	// their type.
+	"alpha": func(c *config) {
+		c.Mode = gorder.ModeAlpha
+		c.Prefixes = nil
+	},
+
+	// strict is hugo with all the tidying rules enabled.
+	"strict": func(c *config) {
+		c.ExportedTypes = true
+		c.StructFields = true
+		c.Errors = gorder.ErrorsBottom
+		c.PinFlags = true
+		c.Normalize = true
+		c.Pairs = strings.Split(gorder.DefaultPairs, ",")
+	},
+}
+
+func profileNames() []string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile applies the named profile to c. The empty name is a no-op.
+func applyProfile(c *config, name string) error {
+	if name == "" {
+		return nil
+	}
+	apply, found := profiles[name]
+	if !found {
+		return fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(profileNames(), ", "))
+	}
+	c.Profile = name
+	apply(c)
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// The Reviewdog Diagnostic Format written with -format=rdjson; see
+// https://github.com/reviewdog/reviewdog/tree/master/proto/rdf.
+type (
+	rdResult struct {
+		Source      rdSource       `json:"source"`
+		Severity    string         `json:"severity"`
+		Diagnostics []rdDiagnostic `json:"diagnostics"`
+	}
+
+	rdSource struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+
+	rdDiagnostic struct {
+		Message     string         `json:"message"`
+		Location    rdLocation     `json:"location"`
+		Severity    string         `json:"severity"`
+		Code        *rdCode        `json:"code,omitempty"`
+		Suggestions []rdSuggestion `json:"suggestions,omitempty"`
+	}
+
+	rdLocation struct {
+		Path  string   `json:"path"`
+		Range *rdRange `json:"range,omitempty"`
+	}
+
+	rdRange struct {
+		Start rdPosition `json:"start"`
+		End   rdPosition `json:"end"`
+	}
+
+	rdPosition struct {
+		Line   int `json:"line"`
+		Column int `json:"column,omitempty"`
+	}
+
+	rdCode struct {
+		Value string `json:"value"`
+	}
+
+	rdSuggestion struct {
+		Range rdRange `json:"range"`
+		Text  string  `json:"text"`
+	}
+)
+
+// rdjsonResult converts reports to a reviewdog result with a diagnostic per
+// changed run of lines, suggesting the reordered lines. The diagnostic lists
+// the findings in the run.
+func rdjsonResult(reports []report) rdResult {
+	res := rdResult{
+		Source:      rdSource{Name: "gorder", URL: "https://github.com/bep/gorder"},
+		Severity:    "WARNING",
+		Diagnostics: []rdDiagnostic{},
+	}
+
+	for _, r := range reports {
+		path := filepath.ToSlash(r.File)
+		if r.Error != nil {
+			d := rdDiagnostic{
+				Message:  r.Error.Message,
+				Location: rdLocation{Path: path},
+				Severity: "ERROR",
+			}
+			if r.Error.Line > 0 {
+				pos := rdPosition{Line: r.Error.Line, Column: r.Error.Column}
+				d.Location.Range = &rdRange{Start: pos, End: pos}
+			}
+			res.Diagnostics = append(res.Diagnostics, d)
+			continue
+		}
+
+		findings := r.findings()
+		for _, h := range r.hunks {
+			// The range ends at the start of the line after the run.
+			rng := rdRange{
+				Start: rdPosition{Line: h.Start, Column: 1},
+				End:   rdPosition{Line: h.Start + h.Len, Column: 1},
+			}
+
+			var messages []string
+			code := orderRule
+			for _, f := range findings {
+				if f.line >= h.Start && f.line < h.Start+h.Len {
+					if messages == nil {
+						code = f.rule
+					}
+					messages = append(messages, f.message)
+				}
+			}
+			if messages == nil {
+				messages = []string{"declarations are out of order"}
+			}
+
+			res.Diagnostics = append(res.Diagnostics, rdDiagnostic{
+				Message:     strings.Join(messages, "; "),
+				Location:    rdLocation{Path: path, Range: &rng},
+				Severity:    "WARNING",
+				Code:        &rdCode{Value: code},
+				Suggestions: []rdSuggestion{{Range: rng, Text: h.Text}},
+			})
+		}
+	}
+
+	return res
+}
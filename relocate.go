@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"os"
+
+	"github.com/dave/dst"
+)
+
+// runRelocate runs the relocate subcommand, moving the methods of the
+// package in dir to the file of their receiver type. It returns exitChanged
+// if methods moved.
+func runRelocate(r *configResolver, args []string) (int, error) {
+	fs := flag.NewFlagSet("relocate", flag.ContinueOnError)
+	dryRun := fs.Bool("n", false, "print the moves without writing the files")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage, err
+	}
+	if fs.NArg() > 1 {
+		return exitUsage, errors.New("usage: gorder relocate [-n] [dir]")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	c, err := r.resolve(dir)
+	if err != nil {
+		return exitUsage, err
+	}
+	p, err := loadPackage(dir, c)
+	if err != nil {
+		return exitFailure, err
+	}
+
+	return applyMoves(p, relocateMoves(p), c, *dryRun)
+}
+
+// relocateMoves returns the moves of the methods in the movable files of p
+// to the file declaring their receiver type or, if declarations cannot move
+// to that one, e.g. a generated file, to the file named for the type.
+func relocateMoves(p *goPackage) []declMove {
+	typeFiles := make(map[string]string)
+	for _, name := range p.names {
+		for _, d := range p.files[name].Decls {
+			if g, ok := d.(*dst.GenDecl); ok && g.Tok == token.TYPE {
+				for _, spec := range g.Specs {
+					typeFiles[spec.(*dst.TypeSpec).Name.Name] = name
+				}
+			}
+		}
+	}
+
+	var (
+		moves  []declMove
+		warned = make(map[string]bool)
+	)
+	for _, name := range p.names {
+		if !p.movable[name] {
+			continue
+		}
+		for _, d := range p.files[name].Decls {
+			f, ok := d.(*dst.FuncDecl)
+			if !ok {
+				continue
+			}
+			t := receiverName(f)
+			to, ok := typeFiles[t]
+			if !ok {
+				continue
+			}
+			if !p.movable[to] {
+				to = typeFilename(t)
+				if !p.canCreate(to) {
+					if !warned[t] {
+						fmt.Fprintf(os.Stderr, "gorder: %s: cannot move the methods to %s\n", t, to)
+						warned[t] = true
+					}
+					continue
+				}
+			}
+			if to != name {
+				moves = append(moves, declMove{decl: d, from: name, to: to})
+			}
+		}
+	}
+	return moves
+}
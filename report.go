@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"html/template"
+	"os"
+	"path/filepath"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// runReport runs the report subcommand, which writes an HTML report of the
+// declaration layout of the files matching the patterns in args, ./... by
+// default, before and after reordering.
+func runReport(r *configResolver, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	output := fs.String("o", "gorder-report.html", "file to write the report to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	var report layoutReport
+	for _, pattern := range patterns {
+		filenames, err := expandPattern(pattern)
+		if err != nil {
+			return err
+		}
+		for _, filename := range filenames {
+			c, err := r.resolve(filepath.Dir(filename))
+			if err != nil {
+				return err
+			}
+			excluded, err := c.excluded(filename)
+			if err != nil {
+				return err
+			}
+			if excluded {
+				continue
+			}
+			l := newFileLayout(filename, c)
+			if len(l.Moves) > 0 {
+				report.Changed++
+				report.Moved += len(l.Moves)
+			}
+			report.Files = append(report.Files, l)
+		}
+	}
+
+	if len(report.Files) == 0 {
+		return errors.New("no files to report on")
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// layoutReport holds the data of the HTML report.
+type layoutReport struct {
+	Files   []fileLayout
+	Changed int
+	Moved   int
+}
+
+// fileLayout is the declaration layout of a file before and after
+// reordering.
+type fileLayout struct {
+	File    string
+	Error   string
+	Skipped string
+	Before  []declLayout
+	After   []declLayout
+	Moves   []gorder.Move
+}
+
+// declLayout is a declaration, marked if it moved.
+type declLayout struct {
+	gorder.Explanation
+	Moved bool
+}
+
+func newFileLayout(filename string, c config) fileLayout {
+	l := fileLayout{File: filename}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		l.Error = err.Error()
+		return l
+	}
+
+	if reason := skipReason(src, c); reason != "" {
+		l.Skipped = reason
+		return l
+	}
+
+	opts, err := fileOptions(filename, src, c)
+	if err == nil {
+		var res gorder.Result
+		if res, err = gorder.Reorder(filename, src, opts); err == nil {
+			l.Moves = res.Moves
+			if l.Before, err = explainLayout(src, opts, l.Moves); err == nil {
+				l.After, err = explainLayout(res.Src, opts, l.Moves)
+			}
+		}
+	}
+	if err != nil {
+		l.Error = err.Error()
+	}
+
+	return l
+}
+
+// explainLayout returns the declarations in src, marking those in moves.
+func explainLayout(src []byte, opts gorder.Options, moves []gorder.Move) ([]declLayout, error) {
+	explanations, err := gorder.Explain(src, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := make(map[[3]string]bool)
+	for _, m := range moves {
+		moved[[3]string{m.Kind, m.Receiver, m.Name}] = true
+	}
+
+	decls := make([]declLayout, len(explanations))
+	for i, e := range explanations {
+		decls[i] = declLayout{Explanation: e, Moved: moved[[3]string{e.Kind, e.Receiver, e.Name}]}
+	}
+	return decls, nil
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gorder report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.1em 0.8em; text-align: left; font-family: monospace; }
+.columns { display: flex; gap: 3em; }
+.moved { background: #fff0c0; }
+.error { color: #b00; }
+.muted { color: #888; }
+summary { cursor: pointer; font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>gorder report</h1>
+<p>{{ len .Files }} files, {{ .Changed }} out of order, {{ .Moved }} declarations to move.</p>
+{{ range .Files }}
+<details{{ if .Moves }} open{{ end }}>
+<summary>{{ .File }}{{ if .Error }} <span class="error">error</span>{{ else if .Skipped }} <span class="muted">{{ .Skipped }}</span>{{ else if .Moves }} ({{ len .Moves }} to move){{ else }} <span class="muted">ok</span>{{ end }}</summary>
+{{ if .Error }}<p class="error">{{ .Error }}</p>{{ else if not .Skipped }}
+<div class="columns">
+<div><h3>Before</h3>{{ template "decls" .Before }}</div>
+<div><h3>After</h3>{{ template "decls" .After }}</div>
+</div>
+{{ end }}
+</details>
+{{ end }}
+</body>
+</html>
+{{ define "decls" }}<table>
+<tr><th>Kind</th><th>Name</th><th>Section</th><th>Weight</th></tr>
+{{ range . }}<tr{{ if .Moved }} class="moved"{{ end }}><td>{{ .Kind }}</td><td>{{ if .Receiver }}{{ .Receiver }}.{{ end }}{{ .Name }}</td><td>{{ .Section }}</td><td>{{ .Weight }}</td></tr>
+{{ end }}</table>{{ end }}
+`))
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// rule is an ordering rule that can be enabled or disabled by its ID.
+type rule struct {
+	doc string
+	set func(o *gorder.Options, on bool)
+}
+
+// rules are the ordering rules by their stable IDs.
+var rules = map[string]rule{
+	"func-order":  kindRule("sort top-level functions and methods", gorder.KindFunc),
+	"type-order":  kindRule("sort type declarations", gorder.KindType),
+	"const-order": kindRule("sort const declarations", gorder.KindConst),
+	"var-order":   kindRule("sort var declarations", gorder.KindVar),
+
+	"interface-methods": kindRule("sort the methods of interface types", gorder.KindInterface),
+
+	"struct-fields": {"sort the fields of struct types", func(o *gorder.Options, on bool) {
+		o.StructFields = on
+		if on {
+			o.Kinds |= gorder.KindStruct
+		}
+	}},
+	"method-grouping": {"keep methods below their receiver type", func(o *gorder.Options, on bool) {
+		o.MethodsAsFuncs = !on
+	}},
+	"struct-literals": {"sort the fields of keyed struct literals", func(o *gorder.Options, on bool) {
+		o.StructLiterals = on
+	}},
+	"map-literals": {"sort the entries of map literals with constant keys", func(o *gorder.Options, on bool) {
+		o.MapLiterals = on
+	}},
+	"exported-types-first": {"place exported types before unexported types", func(o *gorder.Options, on bool) {
+		o.ExportedTypesFirst = on
+	}},
+	"error-section": {"group error declarations in a section, at the top unless set with -errors", func(o *gorder.Options, on bool) {
+		switch {
+		case !on:
+			o.Errors = gorder.ErrorsNone
+		case o.Errors == gorder.ErrorsNone:
+			o.Errors = gorder.ErrorsTop
+		}
+	}},
+	"flags-near-main": {"place flag vars right before func main", func(o *gorder.Options, on bool) {
+		o.FlagsNearMain = on
+	}},
+	"lifecycle-pairs": {"keep lifecycle methods such as Open and Close together", func(o *gorder.Options, on bool) {
+		switch {
+		case !on:
+			o.Pairs = nil
+		case len(o.Pairs) == 0:
+			o.Pairs = gorder.ParsePairs(gorder.DefaultPairs)
+		}
+	}},
+	"helper-placement": {"move helpers below their first caller", func(o *gorder.Options, on bool) {
+		switch {
+		case on:
+			o.Mode = gorder.ModeCaller
+		case o.Mode == gorder.ModeCaller:
+			o.Mode = gorder.ModeDefault
+		}
+	}},
+	"func-vars": {"sort vars holding functions as functions", func(o *gorder.Options, on bool) {
+		o.FuncVars = on
+	}},
+	"grpc-order": {"order gRPC handler methods as in the service interface", func(o *gorder.Options, on bool) {
+		o.GRPC = on
+	}},
+	"blank-lines": {"separate top-level declarations by one blank line", func(o *gorder.Options, on bool) {
+		o.NormalizeSpace = on
+	}},
+	"banners": {"maintain section banner comments", func(o *gorder.Options, on bool) {
+		o.Banners = on
+	}},
+	"outline": {"maintain an outline comment", func(o *gorder.Options, on bool) {
+		o.Outline = on
+	}},
+}
+
+func kindRule(doc string, k gorder.Kind) rule {
+	return rule{doc, func(o *gorder.Options, on bool) {
+		if on {
+			o.Kinds |= k
+		} else {
+			o.Kinds &^= k
+		}
+	}}
+}
+
+func ruleIDs() []string {
+	var ids []string
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// applyRules enables, then disables, the rules with the given IDs in o.
+func applyRules(o *gorder.Options, enable, disable []string) error {
+	for _, ids := range []struct {
+		ids []string
+		on  bool
+	}{{enable, true}, {disable, false}} {
+		for _, id := range ids.ids {
+			r, found := rules[id]
+			if !found {
+				return fmt.Errorf("unknown rule %q, expected one of %s", id, strings.Join(ruleIDs(), ", "))
+			}
+			r.set(o, ids.on)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+)
+
+// The subset of SARIF 2.1.0 written with -format=sarif.
+type (
+	sarifReport struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool        sarifTool         `json:"tool"`
+		Results     []sarifResult     `json:"results"`
+		Invocations []sarifInvocation `json:"invocations"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		RuleIndex int             `json:"ruleIndex"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	sarifInvocation struct {
+		ExecutionSuccessful        bool                `json:"executionSuccessful"`
+		ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+	}
+
+	sarifNotification struct {
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+)
+
+// sarifLog converts reports to a SARIF log with a result per finding and
+// the errors as tool execution notifications.
+func sarifLog(reports []report) sarifReport {
+	ruleIndex := make(map[string]int)
+	var ids []string
+	for _, r := range reports {
+		for _, f := range r.findings() {
+			if _, ok := ruleIndex[f.rule]; !ok {
+				ruleIndex[f.rule] = 0
+				ids = append(ids, f.rule)
+			}
+		}
+	}
+	sort.Strings(ids)
+
+	driver := sarifDriver{
+		Name:           "gorder",
+		InformationURI: "https://github.com/bep/gorder",
+		Rules:          []sarifRule{},
+	}
+	for i, id := range ids {
+		ruleIndex[id] = i
+		driver.Rules = append(driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{ruleDoc(id)}})
+	}
+
+	run := sarifRun{
+		Tool:        sarifTool{Driver: driver},
+		Results:     []sarifResult{},
+		Invocations: []sarifInvocation{{ExecutionSuccessful: true}},
+	}
+	for _, r := range reports {
+		for _, f := range r.findings() {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    f.rule,
+				RuleIndex: ruleIndex[f.rule],
+				Level:     "warning",
+				Message:   sarifMessage{f.message},
+				Locations: []sarifLocation{sarifLocationOf(r.File, f.line, 0)},
+			})
+		}
+		if r.Error != nil {
+			inv := &run.Invocations[0]
+			inv.ExecutionSuccessful = false
+			inv.ToolExecutionNotifications = append(inv.ToolExecutionNotifications, sarifNotification{
+				Level:     "error",
+				Message:   sarifMessage{r.Error.Message},
+				Locations: []sarifLocation{sarifLocationOf(r.File, r.Error.Line, r.Error.Column)},
+			})
+		}
+	}
+
+	return sarifReport{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}
+}
+
+func sarifLocationOf(filename string, line, column int) sarifLocation {
+	uri := filepath.ToSlash(filename)
+	if filepath.IsAbs(filename) {
+		uri = (&url.URL{Scheme: "file", Path: uri}).String()
+	}
+	loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: uri},
+	}}
+	if line > 0 {
+		loc.PhysicalLocation.Region = &sarifRegion{StartLine: line, StartColumn: column}
+	}
+	return loc
+}
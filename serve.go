@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxServeRequest is the size limit of the requests to gorder serve.
+const maxServeRequest = 8 << 20
+
+// runServe runs the serve subcommand, an HTTP server taking reorder requests
+// until interrupted. See serveRequest for the API.
+func runServe(r *configResolver, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("http", "localhost:8080", "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *cacheDir != "" {
+		var err error
+		if cache, err = newFileCache(*cacheDir); err != nil {
+			return err
+		}
+	}
+
+	// The file names in the requests are not paths on this machine, so the
+	// config of the current directory applies to all.
+	c, err := r.resolve(".")
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "listening on http://%s\n", l.Addr())
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /order", orderHandler(c))
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveRequest is the body of a POST /order request: the source of the
+// file File, default input.go, to reorder with the settings in Options
+// applied to the server's config. The settings are keyed by flag name, e.g.
+// {"mode": "caller", "structfields": true, "only": ["func", "type"]}.
+// With Positions, the response holds the line mappings of the
+// declarations. The response is a daemonResponse.
+type serveRequest struct {
+	File      string         `json:"file"`
+	Src       string         `json:"src"`
+	Options   map[string]any `json:"options"`
+	Positions bool           `json:"positions"`
+}
+
+// orderHandler returns the handler of POST /order with the config c.
+func orderHandler(c config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req serveRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxServeRequest))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			status := http.StatusBadRequest
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			writeServeError(w, status, err)
+			return
+		}
+		if req.File == "" {
+			req.File = "input.go"
+		}
+
+		settings, err := serveSettings(req.Options)
+		if err != nil {
+			writeServeError(w, http.StatusBadRequest, err)
+			return
+		}
+		c := c
+		if err := applySettings(settings, &c); err != nil {
+			writeServeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		resp := reorderSource(r.Context(), daemonRequest{File: req.File, Src: req.Src, Positions: req.Positions}, c)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// serveSettings returns the settings in options, sorted by name, with the
+// values as flag values.
+func serveSettings(options map[string]any) ([][2]string, error) {
+	var settings [][2]string
+	for name, v := range options {
+		var value string
+		switch v := v.(type) {
+		case string:
+			value = v
+		case bool:
+			value = strconv.FormatBool(v)
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		case []any:
+			var list []string
+			for _, e := range v {
+				s, ok := e.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid setting %q, expected a list of strings", name)
+				}
+				list = append(list, s)
+			}
+			value = strings.Join(list, ",")
+		default:
+			return nil, fmt.Errorf("invalid setting %q, expected a string, number, bool or list", name)
+		}
+		settings = append(settings, [2]string{name, value})
+	}
+	slices.SortFunc(settings, func(a, b [2]string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	return settings, nil
+}
+
+// writeServeError writes err as the response, as the error of a report.
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report{Error: newReportError(err)})
+}
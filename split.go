@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"os"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+	"github.com/dave/dst"
+)
+
+// declMove is a declaration moved to another file of its package.
+type declMove struct {
+	decl     dst.Decl
+	from, to string
+}
+
+// runSplit runs the split subcommand, moving each exported type in the
+// package in dir, with its constructors and methods, to a file of its own,
+// foo_bar.go for FooBar. It returns exitChanged if declarations moved.
+func runSplit(r *configResolver, args []string) (int, error) {
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	dryRun := fs.Bool("n", false, "print the moves without writing the files")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage, err
+	}
+	if fs.NArg() > 1 {
+		return exitUsage, errors.New("usage: gorder split [-n] [dir]")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	c, err := r.resolve(dir)
+	if err != nil {
+		return exitUsage, err
+	}
+	opts, err := c.options()
+	if err != nil {
+		return exitUsage, err
+	}
+	p, err := loadPackage(dir, c)
+	if err != nil {
+		return exitFailure, err
+	}
+
+	moves := splitMoves(p, opts)
+	return applyMoves(p, moves, c, *dryRun)
+}
+
+// splitMoves returns the moves putting the exported types declared alone
+// in the movable files of p, with their constructors and methods, in the
+// files named for them. The types whose file cannot be created stay.
+func splitMoves(p *goPackage, opts gorder.Options) []declMove {
+	types, decls, files := typeDecls(p, opts, true)
+	var moves []declMove
+	for _, t := range types {
+		to := typeFilename(t)
+		if !p.canCreate(to) {
+			fmt.Fprintf(os.Stderr, "gorder: %s: cannot move to %s\n", t, to)
+			continue
+		}
+		for _, d := range decls[t] {
+			if files[d] != to {
+				moves = append(moves, declMove{decl: d, from: files[d], to: to})
+			}
+		}
+	}
+	return moves
+}
+
+// typeDecls returns the types declared alone in the movable files of p,
+// only the exported ones if exported is set, with the declarations of each:
+// the type, its constructors and its methods in the movable files, in
+// order. It also returns the files of the declarations.
+func typeDecls(p *goPackage, opts gorder.Options, exported bool) ([]string, map[string][]dst.Decl, map[dst.Decl]string) {
+	var (
+		types []string
+		decls = make(map[string][]dst.Decl)
+		files = make(map[dst.Decl]string)
+	)
+	for _, name := range p.names {
+		if !p.movable[name] {
+			continue
+		}
+		for _, d := range p.files[name].Decls {
+			if g, ok := d.(*dst.GenDecl); ok && g.Tok == token.TYPE && len(g.Specs) == 1 {
+				if t := g.Specs[0].(*dst.TypeSpec).Name.Name; token.IsExported(t) || !exported {
+					types = append(types, t)
+					decls[t] = append(decls[t], d)
+					files[d] = name
+				}
+			}
+		}
+	}
+	for _, name := range p.names {
+		if !p.movable[name] {
+			continue
+		}
+		for _, d := range p.files[name].Decls {
+			f, ok := d.(*dst.FuncDecl)
+			if !ok {
+				continue
+			}
+			t := receiverName(f)
+			if t == "" {
+				t = constructedType(f, opts)
+			}
+			if _, ok := decls[t]; ok && t != "" {
+				decls[t] = append(decls[t], d)
+				files[d] = name
+			}
+		}
+	}
+	for _, t := range types {
+		p.sortedDecls(decls[t])
+	}
+	return types, decls, files
+}
+
+// constructedType returns the type f constructs: the type, or the type
+// pointed to, of its first result, if f has a constructor name or with
+// opts.CtorReturn.
+func constructedType(f *dst.FuncDecl, opts gorder.Options) string {
+	if f.Type.Results == nil || len(f.Type.Results.List) == 0 {
+		return ""
+	}
+	if !opts.CtorReturn && !hasConstructorPrefix(f.Name.Name, opts.Constructors) {
+		return ""
+	}
+	return baseTypeName(f.Type.Results.List[0].Type)
+}
+
+// hasConstructorPrefix reports whether name starts with one of prefixes,
+// e.g. New matching both NewFoo and newFoo.
+func hasConstructorPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			continue
+		}
+		lower := strings.ToLower(prefix[:1]) + prefix[1:]
+		if strings.HasPrefix(name, prefix) || strings.HasPrefix(name, lower) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyMoves prints the moves and, unless dryRun, makes them and writes
+// the files of p. It returns exitChanged if there are moves.
+func applyMoves(p *goPackage, moves []declMove, c config, dryRun bool) (int, error) {
+	for _, m := range moves {
+		if !*quiet {
+			fmt.Printf("%s: %s -> %s\n", m.from, declName(m.decl), m.to)
+		}
+		if !dryRun {
+			p.move(m.decl, m.from, m.to)
+		}
+	}
+	if len(moves) == 0 {
+		return exitClean, nil
+	}
+	if dryRun {
+		return exitChanged, nil
+	}
+
+	removed, err := p.write(c)
+	if err != nil {
+		return exitFailure, err
+	}
+	for _, name := range removed {
+		if !*quiet {
+			fmt.Printf("%s: removed\n", name)
+		}
+	}
+	return exitChanged, nil
+}
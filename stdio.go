@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// stdioExcluded holds the flags reading or writing files other than stdin
+// and stdout, or the environment, which -stdio rejects.
+var stdioExcluded = []string{
+	"w", "d", "config", "format", "patch", "manifest", "zip", "cache", "stream", "summary",
+	"cpuprofile", "memprofile", "trace", "insert", "grpc", "align", "hunks", "overlay", "modules", "positions",
+}
+
+// runStdio runs gorder -stdio, which reorders the source on stdin to
+// stdout for hermetic build rules. The settings come from the flags alone,
+// not from config files or the environment, and no other file is read or
+// written. The file name in args, which is not read, names the file in the
+// errors and tells test files apart. Files left as is, e.g. cgo files,
+// are written unchanged. It returns the exit code, exitClean unless
+// it fails, whether the source changed or not.
+func runStdio(args []string) (int, error) {
+	var used []string
+	flag.Visit(func(f *flag.Flag) {
+		for _, name := range stdioExcluded {
+			if f.Name == name {
+				used = append(used, "-"+name)
+			}
+		}
+	})
+	if len(used) > 0 {
+		return exitUsage, fmt.Errorf("the -stdio flag cannot be used with %s", strings.Join(used, ", "))
+	}
+	if len(args) > 1 {
+		return exitUsage, errors.New("usage: gorder -stdio [flags] [filename]")
+	}
+	filename := "<standard input>"
+	if len(args) == 1 {
+		filename = args[0]
+	}
+
+	c, err := newConfigResolver(flag.CommandLine, &cfg, nil, "", true).resolve(".")
+	if err != nil {
+		return exitUsage, err
+	}
+	if _, err := c.options(); err != nil {
+		return exitUsage, err
+	}
+
+	src, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return exitFailure, err
+	}
+
+	out := src
+	if skipReason(src, c) == "" {
+		opts, err := fileOptions(filename, src, c)
+		if err != nil {
+			return exitFailure, err
+		}
+		if opts.InsertOnly || opts.GRPC {
+			return exitFailure, fmt.Errorf("%s: the insert and grpc settings read other files and cannot be used with -stdio", filename)
+		}
+		r, err := gorder.Reorder(filename, src, opts)
+		if err != nil {
+			return exitFailure, fmt.Errorf("%s: %w", filename, err)
+		}
+		out = r.Src
+	}
+
+	if _, err := os.Stdout.Write(out); err != nil {
+		return exitFailure, err
+	}
+	return exitClean, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// streamWindow is the number of files, per job, that -stream lets
+// processing run ahead of the first file not yet done.
+const streamWindow = 4
+
+// emitter passes reports to emit in the order of the files, as soon as the
+// report of each file and those of the files before it are done, then drops
+// their diffs and hunks. A nil emitter does nothing.
+type emitter struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	reports []report
+	done    []bool
+	next    int // The first file not yet emitted.
+	window  int
+	emit    func(report)
+}
+
+func newEmitter(reports []report, emit func(report), window int) *emitter {
+	e := &emitter{reports: reports, done: make([]bool, len(reports)), window: window, emit: emit}
+	e.cond = sync.NewCond(&e.mu)
+	return e
+}
+
+// wait blocks until the file i is at most the window ahead of the first
+// file not yet emitted. The files before i must have been started.
+func (e *emitter) wait(i int) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i-e.next > e.window {
+		e.cond.Wait()
+	}
+}
+
+// finish marks the file i done, processed or not, and emits the reports
+// now in order.
+func (e *emitter) finish(i int) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.done[i] = true
+	for e.next < len(e.done) && e.done[e.next] {
+		e.emitNext()
+	}
+	e.cond.Broadcast()
+}
+
+// flush emits the reports left, of the files processed, when stopping
+// early.
+func (e *emitter) flush() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for e.next < len(e.done) {
+		e.emitNext()
+	}
+}
+
+func (e *emitter) emitNext() {
+	r := &e.reports[e.next]
+	if r.File != "" {
+		e.emit(*r)
+		r.text, r.hunks = nil, nil
+	}
+	e.next++
+}
+
+// streamReport prints r as soon as its file is done with -stream: the
+// error in text format, the diff with -d and the findings or explanation
+// with -format.
+func streamReport(r report, out output) {
+	if out.format == formatText && r.Error != nil {
+		printErrors(os.Stderr, []report{r})
+	}
+	if out.quiet {
+		return
+	}
+	switch out.format {
+	case formatGitHub:
+		writeGitHub(os.Stdout, []report{r})
+	case formatExplain:
+		os.Stdout.Write(r.text)
+	default:
+		if out.diff {
+			os.Stdout.Write(r.text)
+		}
+	}
+}
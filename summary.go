@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+)
+
+// printSummary writes a summary of a run to w: the number of files matched,
+// changed and skipped, and the declarations moved.
+func printSummary(w io.Writer, matched, excluded int, reports []report, elapsed time.Duration) {
+	writeSummary(w, matched, excluded, reports)
+	fmt.Fprintf(w, " in %s\n", elapsed.Round(time.Millisecond))
+}
+
+// printModuleSummaries writes a summary per module to w, as printSummary
+// does, followed by the summary of the whole run. excluded holds the number
+// of files excluded per module.
+func printModuleSummaries(w io.Writer, excluded map[string]int, reports []report, elapsed time.Duration) {
+	byModule := make(map[string][]report)
+	for _, r := range reports {
+		byModule[r.Module] = append(byModule[r.Module], r)
+	}
+	paths := slices.Collect(maps.Keys(byModule))
+	for path := range excluded {
+		if _, ok := byModule[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	var total int
+	for _, path := range paths {
+		name := path
+		if name == "" {
+			name = "(no module)"
+		}
+		fmt.Fprintf(w, "%s: ", name)
+		writeSummary(w, len(byModule[path])+excluded[path], excluded[path], byModule[path])
+		fmt.Fprintln(w)
+		total += excluded[path]
+	}
+	printSummary(w, len(reports)+total, total, reports, elapsed)
+}
+
+func writeSummary(w io.Writer, matched, excluded int, reports []report) {
+	var changed, moved, generated, cgo, lines, parseErrors, errs int
+	for _, r := range reports {
+		switch {
+		case r.Skipped == skippedGenerated:
+			generated++
+		case r.Skipped == skippedCgo:
+			cgo++
+		case r.Skipped == skippedLines:
+			lines++
+		case r.Error != nil && r.Error.Line > 0:
+			parseErrors++
+		case r.Error != nil:
+			errs++
+		case r.Changed:
+			changed++
+		}
+		moved += len(r.Moves)
+	}
+
+	skipped := excluded + generated + cgo + lines + parseErrors
+	fmt.Fprintf(w, "%d files scanned, %d changed, %d declarations moved, %d skipped (%d generated, %d cgo, %d with //line directives, %d excluded, %d parse errors)",
+		matched, changed, moved, skipped, generated, cgo, lines, excluded, parseErrors)
+	if errs > 0 {
+		fmt.Fprintf(w, ", %d failed", errs)
+	}
+}
@@ -0,0 +1,12 @@
+// The build constraints below must stay above the package clause, followed
+// by a blank line, when the declarations move.
+
+//go:build go1.18
+// +build go1.18
+
+package testing
+
+func zedConstrained() {}
+
+// AlphaConstrained is exported.
+func AlphaConstrained() {}
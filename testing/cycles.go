@@ -0,0 +1,34 @@
+package testing
+
+func Run() {
+	start()
+}
+
+func start() {
+	step()
+}
+
+func pong(n int) {
+	if n > 0 {
+		ping(n - 1)
+	}
+}
+
+func ping(n int) {
+	if n > 0 {
+		pong(n - 1)
+	}
+	log()
+}
+
+func log() {}
+
+func step() {}
+
+func even(n int) bool {
+	return n == 0 || odd(n-1)
+}
+
+func odd(n int) bool {
+	return n != 0 && even(n-1)
+}
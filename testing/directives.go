@@ -0,0 +1,13 @@
+package testing
+
+//go:generate stringer -type=directiveKind
+
+type directiveKind int
+
+//go:noinline
+func zedDirective() int { return 1 } //nolint:unused
+
+// AlphaDirective has a doc comment above its directive.
+//
+//go:noinline
+func AlphaDirective() int { return zedDirective() }
@@ -0,0 +1,31 @@
+package testing
+
+type Number interface {
+	~int64 | ~float64
+	~int | ~int32
+}
+
+type Embedding interface {
+	Stringer
+	Zed()
+	Getter[int]
+	Reader
+	Alpha()
+	Pair[string, int]
+}
+
+type EmbeddingStruct struct {
+	Pair[string, int]
+	*Container[int]
+	z int
+}
+
+type Container[T any] struct{ v T }
+
+type Pair[K comparable, V any] interface{ Key() K }
+
+type Stringer interface{ String() string }
+
+type Reader interface{ Read(p []byte) (int, error) }
+
+type Getter[T any] interface{ Get() T }
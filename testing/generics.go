@@ -0,0 +1,15 @@
+package testing
+
+func (s *Set[T]) zed() {}
+
+type Map[K comparable, V any] struct{ m map[K]V }
+
+func helper() {}
+
+func (m Map[K, V]) Get(k K) V { return m.m[k] }
+
+type Set[T comparable] map[T]struct{}
+
+func (s Set[T]) Add(v T) { s[v] = struct{}{} }
+
+func (m *Map[K, V]) Alpha() {}
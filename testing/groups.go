@@ -0,0 +1,16 @@
+package testing
+
+func (b GroupedB) Zed() {}
+
+type ()
+
+var ()
+
+type (
+	groupedA int
+	GroupedB struct{}
+)
+
+func (b GroupedB) Alpha() {}
+
+func groupedHelper() {}
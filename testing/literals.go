@@ -0,0 +1,30 @@
+package testing
+
+type Options struct {
+	Verbose bool
+	Name    string
+	Level   int
+	Hook    func()
+}
+
+var defaultName = "gorder"
+
+var Defaults = Options{
+	Verbose: true,
+	Name:    defaultName,
+	Level:   1,
+	Hook:    func() {},
+}
+
+var calls int
+
+func next() int {
+	calls++
+	return calls
+}
+
+// The calls are made in this order.
+var Counted = Options{
+	Level: next(),
+	Name:  "counted",
+}
@@ -0,0 +1,31 @@
+package testing
+
+import (
+	_ "unsafe"
+)
+
+func Zeta() {}
+
+// nanotime is implemented by the runtime.
+//
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+func Alpha() {}
+
+type Clock struct{}
+
+//go:linkname fastrand runtime.fastrand
+func fastrand() uint32
+
+func NewClock() *Clock {
+	return &Clock{}
+}
+
+func (c *Clock) Now() int64 {
+	return nanotime()
+}
+
+func Beta() uint32 {
+	return fastrand()
+}
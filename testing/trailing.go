@@ -0,0 +1,9 @@
+package testing
+
+func zedTrailing() {}
+
+func AlphaTrailing() {}
+
+// func oldTrailing() {}
+
+// vim: set ts=4:
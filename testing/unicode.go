@@ -0,0 +1,15 @@
+package testing
+
+func ärgerHelper() {}
+
+type Ärger struct{}
+
+func (Ärger) ĄParse() {}
+
+func NeuÄrger() Ärger { return Ärger{} }
+
+func ĄParse() {}
+
+func αHelper() {}
+
+func ǅTitle() {}
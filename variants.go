@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Known GOOS and GOARCH values, as used in file name build constraints.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+		"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+		"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+		"windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+		"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+		"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+		"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true,
+		"sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// variantGroup returns the name shared by platform variants of filename:
+// foo.go, foo_linux.go and foo_windows_amd64.go all belong to dir/foo.go.
+func variantGroup(filename string) string {
+	dir, base := filepath.Split(filename)
+	name := strings.TrimSuffix(base, ".go")
+
+	test := strings.HasSuffix(name, "_test")
+	name = strings.TrimSuffix(name, "_test")
+
+	parts := strings.Split(name, "_")
+	n := len(parts)
+	if n > 1 && knownArch[parts[n-1]] {
+		n--
+	}
+	if n > 1 && knownOS[parts[n-1]] {
+		n--
+	}
+	name = strings.Join(parts[:n], "_")
+
+	if test {
+		name += "_test"
+	}
+
+	return dir + name + ".go"
+}
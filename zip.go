@@ -0,0 +1,63 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+
+	"github.com/bep/gorder/gorder"
+)
+
+// handleZip reorders the files matching pattern in the zip archive filename
+// and writes a copy of the archive with the results to stdout. The config is
+// resolved for the current directory; excluded and unmatched files are
+// copied as is.
+func handleZip(r *configResolver, filename, pattern string) error {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	c, err := r.resolve(".")
+	if err != nil {
+		return err
+	}
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+
+	results := make(map[string]gorder.Result)
+	err = gorder.ReorderFSFunc(zr, pattern, opts, func(name string, res gorder.Result) error {
+		excluded, err := c.excluded(name)
+		if err != nil || excluded {
+			return err
+		}
+		results[name] = res
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(os.Stdout)
+	for _, f := range zr.File {
+		res, ok := results[f.Name]
+		if !ok {
+			if err := zw.Copy(f); err != nil {
+				return err
+			}
+			continue
+		}
+		h := f.FileHeader
+		w, err := zw.CreateHeader(&h)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(res.Src); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}